@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// multiFile последовательно читает файлы как один поток строк.
+// Если файл не оканчивается переводом строки, он добавляется,
+// чтобы последняя строка файла не склеилась с первой строкой следующего.
+type multiFile struct {
+	files []string
+	cur   io.ReadCloser
+	last  byte
+}
+
+// Функция openFiles возвращает поток строк всех файлов, файлы открываются по мере чтения.
+// Пустой список означает Stdin.
+func openFiles(files []string) io.ReadCloser {
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	return &multiFile{files: files, last: '\n'}
+}
+
+func (m *multiFile) Read(p []byte) (int, error) {
+	for {
+		if m.cur == nil {
+			if len(m.files) == 0 {
+				return 0, io.EOF
+			}
+			f, err := openFile(m.files[0])
+			if err != nil {
+				return 0, fmt.Errorf("cannot read: %w", err)
+			}
+			m.cur, m.files = f, m.files[1:]
+		}
+		n, err := m.cur.Read(p)
+		if n > 0 {
+			m.last = p[n-1]
+			return n, nil
+		}
+		if err == io.EOF {
+			m.cur.Close()
+			m.cur = nil
+			if m.last != '\n' && len(p) > 0 {
+				p[0], m.last = '\n', '\n'
+				return 1, nil
+			}
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
+func (m *multiFile) Close() error {
+	if m.cur != nil {
+		return m.cur.Close()
+	}
+	return nil
+}
+
+// Функция openFile открывает файл для чтения, для имени "-" или пустого имени возвращает Stdin
+func openFile(file string) (io.ReadCloser, error) {
+	if file == "" || file == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(file)
+}
+
+// maxNameSize - максимальная длина имени файла в --files0-from
+const maxNameSize = 1 << 30
+
+// Функция readFiles0 читает имена файлов, разделенные нулевым байтом, из файла file (--files0-from)
+func readFiles0(file string) ([]string, error) {
+	f, err := openFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open '%s' for reading: %w", file, err)
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 4096), maxNameSize)
+	sc.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		if i := bytes.IndexByte(data, 0); i >= 0 {
+			return i + 1, data[:i], nil
+		}
+		if atEOF && len(data) > 0 {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	})
+
+	var files []string
+	for n := 1; sc.Scan(); n++ {
+		name := sc.Text()
+		switch {
+		case name == "":
+			return nil, fmt.Errorf("%s:%d: invalid zero-length file name", file, n)
+		case name == "-" && (file == "-" || file == ""):
+			return nil, errors.New("when reading file names from stdin, no file name of '-' allowed")
+		}
+		files = append(files, name)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no input from '%s'", file)
+	}
+	return files, nil
+}
+
+// lazyFile открывает файл вывода (-o) только при первой записи или закрытии,
+// поэтому вывод может совпадать с входным файлом, прочитанным до начала записи
+type lazyFile struct {
+	name string
+	f    *os.File
+}
+
+func (l *lazyFile) open() error {
+	if l.f != nil {
+		return nil
+	}
+	f, err := os.Create(l.name)
+	if err != nil {
+		return fmt.Errorf("open failed: %w", err)
+	}
+	l.f = f
+	return nil
+}
+
+func (l *lazyFile) Write(p []byte) (int, error) {
+	if err := l.open(); err != nil {
+		return 0, err
+	}
+	return l.f.Write(p)
+}
+
+// Close создает файл, если в него ничего не было записано, и закрывает его
+func (l *lazyFile) Close() error {
+	if err := l.open(); err != nil {
+		return err
+	}
+	return l.f.Close()
+}
+
+// Функция isSameFile возвращает true, если a и b - один и тот же существующий файл
+func isSameFile(a, b string) bool {
+	if a == "-" || b == "-" {
+		return false
+	}
+	ai, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	bi, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(ai, bi)
+}
+
+// Функция copyToTemp копирует файл во временный файл в каталоге dir и возвращает его имя.
+// Используется при слиянии (-m), если вывод совпадает с одним из входных файлов.
+func copyToTemp(file, dir string) (string, error) {
+	in, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+	out, err := os.CreateTemp(dir, "sort")
+	if err != nil {
+		return "", err
+	}
+	_, err = io.Copy(out, in)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(out.Name())
+		return "", err
+	}
+	return out.Name(), nil
+}
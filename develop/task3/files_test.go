@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeFiles создает во временном каталоге файлы с содержимым contents и возвращает их имена
+func writeFiles(t *testing.T, contents ...string) []string {
+	t.Helper()
+	dir := t.TempDir()
+	files := make([]string, len(contents))
+	for i, c := range contents {
+		files[i] = filepath.Join(dir, fmt.Sprintf("in%02d.txt", i))
+		if err := os.WriteFile(files[i], []byte(c), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return files
+}
+
+func TestOpenFiles(t *testing.T) {
+	files := writeFiles(t, "a\nb", "", "c\n", "d")
+	f := openFiles(files)
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatalf("io.ReadAll: %v", err)
+	}
+	if expected := "a\nb\nc\nd\n"; string(data) != expected {
+		t.Errorf("openFiles(%q) = %q, expected %q", files, data, expected)
+	}
+}
+
+func TestOpenFilesMissing(t *testing.T) {
+	f := openFiles([]string{filepath.Join(t.TempDir(), "missing.txt")})
+	defer f.Close()
+	if _, err := io.ReadAll(f); err == nil {
+		t.Error("reading missing file: expected error")
+	}
+}
+
+func TestReadFiles0(t *testing.T) {
+	list := writeFiles(t, "a.txt\x00dir/b c.txt\x00-\x00")
+	files, err := readFiles0(list[0])
+	expected := []string{"a.txt", "dir/b c.txt", "-"}
+	if err != nil || !reflect.DeepEqual(files, expected) {
+		t.Errorf("readFiles0 = %q, %v, expected %q, nil", files, err, expected)
+	}
+
+	for _, c := range []string{"", "a\x00\x00b"} {
+		list := writeFiles(t, c)
+		if _, err := readFiles0(list[0]); err == nil {
+			t.Errorf("readFiles0 of %q: expected error", c)
+		}
+	}
+}
+
+func TestExecuteOutputToInput(t *testing.T) {
+	defer setFlags(t, nil)
+
+	files := writeFiles(t, "c\na\n", "b\n")
+	setFlags(t, []string{"-o", files[0], files[0], files[1]})
+	if err := execute(); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if data, _ := os.ReadFile(files[0]); string(data) != "a\nb\nc\n" {
+		t.Errorf("sort -o: output = %q, expected %q", data, "a\nb\nc\n")
+	}
+
+	files = writeFiles(t, "a\nc\n", "b\nd\n")
+	setFlags(t, []string{"-m", "-o", files[1], files[0], files[1]})
+	if err := execute(); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if data, _ := os.ReadFile(files[1]); string(data) != "a\nb\nc\nd\n" {
+		t.Errorf("sort -m -o: output = %q, expected %q", data, "a\nb\nc\nd\n")
+	}
+
+	// заголовок не выводится до чтения всего входа, иначе файл будет обрезан
+	var input strings.Builder
+	input.WriteString("n\n")
+	for i := 100000; i > 0; i-- {
+		fmt.Fprintln(&input, i)
+	}
+	files = writeFiles(t, input.String())
+	setFlags(t, []string{"--header", "-n", "-o", files[0], files[0]})
+	if err := execute(); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	data, _ := os.ReadFile(files[0])
+	if lines := strings.Split(string(data), "\n"); len(lines) != 100002 || lines[0] != "n" || lines[1] != "1" || lines[100000] != "100000" {
+		t.Errorf("sort --header -n -o: output has %d lines, expected the header and 100000 sorted lines", len(lines)-1)
+	}
+}
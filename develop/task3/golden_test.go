@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Эталонные файлы testdata/*.golden получены командой
+// LC_ALL=C sort ARGS testdata/INPUT (GNU coreutils 9.1), по умолчанию INPUT - input.txt.
+var goldenCases = []struct {
+	name  string
+	args  []string
+	input string
+}{
+	{"default", nil, ""},
+	{"reverse", []string{"-r"}, ""},
+	{"unique", []string{"-u"}, ""},
+	{"key2n", []string{"-k2,2n"}, ""},
+	{"key2n_stable", []string{"-k2,2n", "-s"}, ""},
+	{"key2n_reverse", []string{"-k2,2n", "-r"}, ""},
+	{"key2nr_key1", []string{"-k2,2nr", "-k1,1"}, ""},
+	{"key3M", []string{"-k3,3M"}, ""},
+	{"key3M_stable", []string{"-s", "-k3,3M"}, ""},
+	{"key4h", []string{"-k4,4h"}, ""},
+	{"key1_unique", []string{"-k1,1", "-u"}, ""},
+	{"unique_key2n", []string{"-u", "-k2,2n"}, ""},
+	{"unique_fold", []string{"-uf"}, ""},
+	{"blanks_key2", []string{"-b", "-k2,2"}, ""},
+	{"key2_key1_stable", []string{"-s", "-k2,2", "-k1,1"}, ""},
+	{"tab_space_key2", []string{"-t", " ", "-k2,2"}, ""},
+	{"key1char2", []string{"-k1.2"}, ""},
+	{"numeric_reverse_stable", []string{"-nrs", "-k2,2"}, ""},
+	{"month_reverse", []string{"-M", "-k3,3", "-r"}, ""},
+	{"fold", []string{"-f"}, ""},
+	{"fold_dict_key3", []string{"-k3,3df"}, ""},
+	{"numeric", []string{"-n"}, "numbers.txt"},
+	{"numeric_reverse", []string{"-nr"}, "numbers.txt"},
+	{"numeric_stable", []string{"-ns"}, "numbers.txt"},
+	{"numeric_big", []string{"-n"}, "bignum.txt"},
+	{"key1char2_bytes", []string{"-k1.2,1.3"}, "utf8.txt"},
+	{"human_sign", []string{"-h"}, "human.txt"},
+	{"key1char2bn", []string{"-k1.2b,2.3n"}, "numkeys.txt"},
+	{"version", []string{"-V"}, "versions.txt"},
+	{"version_key_reverse", []string{"-k1.2Vr"}, "versions.txt"},
+}
+
+// setFlags сбрасывает флаги к значениям по умолчанию и разбирает args
+func setFlags(t *testing.T, args []string) {
+	t.Helper()
+	keys = nil
+	flag.VisitAll(func(f *flag.Flag) {
+		if f.Name != "k" && !strings.HasPrefix(f.Name, "test.") {
+			f.Value.Set(f.DefValue)
+		}
+	})
+	if err := flag.CommandLine.Parse(expandArgs(args)); err != nil {
+		t.Fatalf("flag.Parse(%q): %v", args, err)
+	}
+}
+
+func TestGolden(t *testing.T) {
+	defer setFlags(t, nil)
+	for _, c := range goldenCases {
+		input := filepath.Join("testdata", "input.txt")
+		if c.input != "" {
+			input = filepath.Join("testdata", c.input)
+		}
+		setFlags(t, c.args)
+		opts, err := optionsFromFlags()
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+
+		expected, err := os.ReadFile(filepath.Join("testdata", c.name+".golden"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var buf bytes.Buffer
+		if err := sortFiles([]string{input}, &buf, opts); err != nil {
+			t.Fatalf("%s: sortFiles: %v", c.name, err)
+		}
+		if !bytes.Equal(buf.Bytes(), expected) {
+			t.Errorf("%s: sort %q:\n%s\nexpected:\n%s", c.name, c.args, buf.Bytes(), expected)
+		}
+
+		// внешняя сортировка должна давать тот же результат
+		opts.BufferSize = 100
+		buf.Reset()
+		if err := sortFiles([]string{input}, &buf, opts); err != nil {
+			t.Fatalf("%s: sortFiles with -S: %v", c.name, err)
+		}
+		if !bytes.Equal(buf.Bytes(), expected) {
+			t.Errorf("%s: sort -S 100b %q:\n%s\nexpected:\n%s", c.name, c.args, buf.Bytes(), expected)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package sorter
+
+import (
+	"sync"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+// collation вычисляет ключи сравнения строк по правилам Unicode Collation Algorithm
+// для заданного языка. collate.Collator не безопасен для одновременного использования,
+// поэтому для горутин сортировки держится пул.
+type collation struct {
+	pool sync.Pool
+}
+
+// Функция newCollation возвращает правила сравнения для языка lang, например "ru" или "en"
+func newCollation(lang string) (*collation, error) {
+	tag, err := language.Parse(lang)
+	if err != nil {
+		return nil, err
+	}
+	c := &collation{}
+	c.pool.New = func() any { return collate.New(tag) }
+	return c, nil
+}
+
+// key возвращает ключ строки s, побайтовое сравнение ключей соответствует сравнению строк
+func (c *collation) key(s string) string {
+	col := c.pool.Get().(*collate.Collator)
+	defer c.pool.Put(col)
+	var buf collate.Buffer
+	return string(col.KeyFromString(&buf, s))
+}
@@ -0,0 +1,36 @@
+package sorter
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCollationRussian(t *testing.T) {
+	s := mustSorter(t, Options{Locale: "ru"})
+
+	lines := recordLines(s.sortRecords([]string{"яблоко", "ёлка", "Елка", "елка", "жук", "Арбуз", "абрикос"}))
+
+	expected := []string{"абрикос", "Арбуз", "елка", "Елка", "ёлка", "жук", "яблоко"}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("sorted lines = %q, expected %q", lines, expected)
+	}
+}
+
+func TestCollationFoldKey(t *testing.T) {
+	k, _ := ParseKey("2,2f")
+	s := mustSorter(t, Options{Keys: []Key{k}, Locale: "en"})
+
+	// равные без учета регистра ключи упорядочиваются последним сравнением строк
+	lines := recordLines(s.sortRecords([]string{"3 b", "2 B", "1 a", "4 résumé", "5 resume"}))
+
+	expected := []string{"1 a", "2 B", "3 b", "5 resume", "4 résumé"}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("sorted lines = %q, expected %q", lines, expected)
+	}
+}
+
+func TestNewCollationInvalid(t *testing.T) {
+	if _, err := newCollation("not a locale!"); err == nil {
+		t.Error("newCollation(\"not a locale!\"): expected error")
+	}
+}
@@ -0,0 +1,295 @@
+package sorter
+
+import (
+	"bufio"
+	"container/heap"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// mergeBatch - максимальное число отсортированных частей, сливаемых за один проход
+const mergeBatch = 16
+
+// lineOverhead - оценка памяти, занимаемой строкой в слайсе помимо ее байтов
+const lineOverhead = 16
+
+// ParseSize разбирает размер буфера в формате флага -S GNU sort:
+// число с необязательным суффиксом b, K, M, G, T, P или E, без суффикса - килобайты
+func ParseSize(s string) (int64, error) {
+	n := len(s)
+	for n > 0 && (s[n-1] < '0' || s[n-1] > '9') {
+		n--
+	}
+	v, err := strconv.ParseInt(s[:n], 10, 64)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid -S argument '%s'", s)
+	}
+
+	mul := int64(1)
+	switch suffix := strings.ToUpper(s[n:]); suffix {
+	case "B":
+	case "", "K":
+		mul = 1 << 10
+	case "M":
+		mul = 1 << 20
+	case "G":
+		mul = 1 << 30
+	case "T":
+		mul = 1 << 40
+	case "P":
+		mul = 1 << 50
+	case "E":
+		mul = 1 << 60
+	default:
+		return 0, fmt.Errorf("invalid -S argument '%s'", s)
+	}
+	if v > (1<<63-1)/mul {
+		return 0, fmt.Errorf("-S argument '%s' too large", s)
+	}
+	return v * mul, nil
+}
+
+// writeRun сохраняет отсортированную часть во временный файл в каталоге s.dir
+// и возвращает имя файла
+func (s *sorter) writeRun(recs []record) (string, error) {
+	f, err := os.CreateTemp(s.dir, "sort")
+	if err != nil {
+		return "", err
+	}
+	if err := s.writeRecords(f, recs, false); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), f.Close()
+}
+
+// sortExternal сортирует строки сканера, используя не более s.limit байт памяти под строки.
+// Части входа сортируются в памяти и сохраняются во временные файлы в каталоге s.dir,
+// которые затем сливаются. Если вход поместился в память, временные файлы не создаются.
+func (s *sorter) sortExternal(scanner *bufio.Scanner, w io.Writer) error {
+	var runs []string
+	defer func() {
+		for _, name := range runs {
+			os.Remove(name)
+		}
+	}()
+
+	var chunk []string
+	var size int64
+	flush := func() error {
+		// с Unique повторы внутри части отбрасываются при записи, между частями - при слиянии
+		name, err := s.writeRun(s.sortRecords(chunk))
+		if err != nil {
+			return err
+		}
+		runs = append(runs, name)
+		chunk, size = nil, 0
+		return nil
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		chunk = append(chunk, line)
+		size += int64(len(line)) + lineOverhead
+		if size >= s.limit {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if len(runs) == 0 {
+		return s.writeRecords(w, s.sortRecords(chunk), true)
+	}
+	if len(chunk) > 0 {
+		if err := flush(); err != nil {
+			return err
+		}
+	}
+
+	return s.mergeFiles(runs, w, false)
+}
+
+// mergeFiles сливает отсортированные файлы в w. Если файлов больше mergeBatch,
+// слияние выполняется в несколько проходов через временные файлы в каталоге s.dir,
+// чтобы не открывать слишком много файлов одновременно. С header первая запись
+// каждого файла считается заголовком.
+func (s *sorter) mergeFiles(files []string, w io.Writer, header bool) error {
+	var temps []string
+	defer func() {
+		for _, name := range temps {
+			os.Remove(name)
+		}
+	}()
+
+	for len(files) > mergeBatch {
+		var merged []string
+		for i := 0; i < len(files); i += mergeBatch {
+			name, err := s.mergeToRun(files[i:minInt(i+mergeBatch, len(files))], header)
+			if err != nil {
+				return err
+			}
+			merged = append(merged, name)
+		}
+		// временные файлы предыдущего прохода больше не нужны
+		for _, name := range temps {
+			os.Remove(name)
+		}
+		files, temps = merged, merged
+	}
+	return s.mergeRuns(files, w, header, true)
+}
+
+// mergeToRun сливает части runs в новый временный файл и возвращает его имя
+func (s *sorter) mergeToRun(runs []string, header bool) (string, error) {
+	f, err := os.CreateTemp(s.dir, "sort")
+	if err != nil {
+		return "", err
+	}
+	err = s.mergeRuns(runs, f, header, false)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// run - отсортированная часть входа, читаемая из файла
+type run struct {
+	sc  *bufio.Scanner
+	rec record
+	idx int
+}
+
+// next читает очередную строку части, возвращает false, если строки закончились
+func (r *run) next(s *sorter) (bool, error) {
+	if !r.sc.Scan() {
+		return false, r.sc.Err()
+	}
+	r.rec = s.makeRecord(r.sc.Text())
+	return true, nil
+}
+
+// runHeap реализует heap.Interface для k-путевого слияния частей.
+// При равенстве строк первой идет строка из более ранней части входа.
+type runHeap struct {
+	runs []*run
+	s    *sorter
+}
+
+func (h *runHeap) Len() int      { return len(h.runs) }
+func (h *runHeap) Swap(i, j int) { h.runs[i], h.runs[j] = h.runs[j], h.runs[i] }
+func (h *runHeap) Less(i, j int) bool {
+	if c := h.s.compare(&h.runs[i].rec, &h.runs[j].rec); c != 0 {
+		return c < 0
+	}
+	return h.runs[i].idx < h.runs[j].idx
+}
+func (h *runHeap) Push(x any) { h.runs = append(h.runs, x.(*run)) }
+func (h *runHeap) Pop() any {
+	r := h.runs[len(h.runs)-1]
+	h.runs = h.runs[:len(h.runs)-1]
+	return r
+}
+
+// mergeRuns сливает отсортированные части из файлов runs и записывает результат в w.
+// Имя "-" означает Stdin, final - слияние окончательное (см. newGroupWriter).
+func (s *sorter) mergeRuns(runs []string, w io.Writer, header, final bool) error {
+	rs := make([]io.Reader, len(runs))
+	for i, name := range runs {
+		if name == "-" {
+			rs[i] = os.Stdin
+			continue
+		}
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		rs[i] = f
+	}
+	return s.mergeReaders(rs, w, header, final)
+}
+
+// mergeReaders сливает отсортированные входы rs и записывает результат в w.
+// С header первая запись каждого входа считается заголовком, и выводится
+// только первый из заголовков.
+func (s *sorter) mergeReaders(rs []io.Reader, w io.Writer, header, final bool) error {
+	bw, emit := lineWriter(w)
+	h := &runHeap{s: s}
+	scanners := make([]*bufio.Scanner, len(rs))
+	for i, rd := range rs {
+		scanners[i] = s.newScanner(rd)
+	}
+	if header {
+		if err := s.mergeHeaders(scanners, bw); err != nil {
+			return err
+		}
+	}
+	for i, sc := range scanners {
+		r := &run{sc: sc, idx: i}
+		ok, err := r.next(s)
+		if err != nil {
+			return err
+		}
+		if ok {
+			h.runs = append(h.runs, r)
+		}
+	}
+	heap.Init(h)
+
+	g := s.newGroupWriter(emit, final)
+	for h.Len() > 0 {
+		r := h.runs[0]
+		if err := g.write(r.rec); err != nil {
+			return err
+		}
+		ok, err := r.next(s)
+		if err != nil {
+			return err
+		}
+		if ok {
+			heap.Fix(h, 0)
+		} else {
+			heap.Pop(h)
+		}
+	}
+	if err := g.flush(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// mergeHeaders читает заголовки входов и записывает в w первый из них
+func (s *sorter) mergeHeaders(scanners []*bufio.Scanner, w io.Writer) error {
+	found := false
+	for _, sc := range scanners {
+		if !sc.Scan() {
+			if err := sc.Err(); err != nil {
+				return err
+			}
+			continue
+		}
+		if found {
+			continue
+		}
+		found = true
+		if err := s.setHeader(sc.Text()); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, sc.Text()+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package sorter
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSize(t *testing.T) {
+	cases := []struct {
+		s        string
+		expected int64
+	}{
+		{"10", 10 << 10},
+		{"100b", 100},
+		{"2K", 2 << 10},
+		{"512k", 512 << 10},
+		{"3M", 3 << 20},
+		{"1G", 1 << 30},
+	}
+	for _, c := range cases {
+		res, err := ParseSize(c.s)
+		if err != nil || res != c.expected {
+			t.Errorf("ParseSize(%q) = %d, %v, expected %d, nil", c.s, res, err, c.expected)
+		}
+	}
+
+	for _, s := range []string{"", "M", "10X", "-1K", "99999999E"} {
+		if _, err := ParseSize(s); err == nil {
+			t.Errorf("ParseSize(%q): expected error", s)
+		}
+	}
+}
+
+// writeRandomFile создает файл из n случайных строк вида "слово число слово"
+func writeRandomFile(t *testing.T, n int) string {
+	t.Helper()
+	words := []string{"alpha", "beta", "gamma", "delta", "Jan", "feb", "MAR", "яблоко", "груша"}
+	r := rand.New(rand.NewSource(1))
+	var buf bytes.Buffer
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&buf, "%s %d %s\n", words[r.Intn(len(words))], r.Intn(100)-50, words[r.Intn(len(words))])
+	}
+	file := filepath.Join(t.TempDir(), "input.txt")
+	if err := os.WriteFile(file, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+// sortInMemory возвращает результат сортировки файла без ограничения памяти
+func sortInMemory(t *testing.T, file string, s *sorter) string {
+	t.Helper()
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	lines, err := s.readLines(s.newScanner(f))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := s.writeRecords(&buf, s.sortRecords(lines), true); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func testSortExternal(t *testing.T, opts Options, specs ...string) {
+	t.Helper()
+	file := writeRandomFile(t, 3000)
+	var ks []Key
+	for _, spec := range specs {
+		k, err := ParseKey(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ks = append(ks, k)
+	}
+
+	// около 20 строк на часть, больше mergeBatch частей
+	dir := t.TempDir()
+	opts.Keys, opts.BufferSize, opts.TempDir = ks, 512, dir
+	s := mustSorter(t, opts)
+	expected := sortInMemory(t, file, s)
+
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var buf bytes.Buffer
+	if err := s.sortExternal(s.newScanner(f), &buf); err != nil {
+		t.Fatalf("sortExternal: %v", err)
+	}
+	if buf.String() != expected {
+		t.Errorf("sortExternal(%q) result differs from in-memory sort", specs)
+	}
+
+	left, _ := os.ReadDir(dir)
+	if len(left) != 0 {
+		t.Errorf("sortExternal left %d temporary files", len(left))
+	}
+}
+
+func TestSortExternal(t *testing.T) {
+	testSortExternal(t, Options{})
+}
+
+func TestSortExternalKeys(t *testing.T) {
+	testSortExternal(t, Options{}, "2,2n", "3,3r", "1,1M")
+}
+
+func TestSortExternalUnique(t *testing.T) {
+	testSortExternal(t, Options{Unique: true}, "1,1", "2,2n")
+	testSortExternal(t, Options{Unique: true}, "3,3")
+	testSortExternal(t, Options{Unique: true, FoldCase: true}, "3,3")
+}
+
+func TestSortExternalCount(t *testing.T) {
+	testSortExternal(t, Options{Count: true}, "1,1f")
+	testSortExternal(t, Options{Duplicates: true}, "2,2n")
+	testSortExternal(t, Options{Count: true, Duplicates: true}, "1,1", "3,3")
+}
+
+func TestSortExternalFitsInMemory(t *testing.T) {
+	file := writeRandomFile(t, 100)
+	dir := t.TempDir()
+	s := mustSorter(t, Options{BufferSize: 1 << 20, TempDir: dir})
+
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var buf bytes.Buffer
+	if err := s.sortExternal(s.newScanner(f), &buf); err != nil {
+		t.Fatalf("sortExternal: %v", err)
+	}
+	if buf.String() != sortInMemory(t, file, s) {
+		t.Error("sortExternal result differs from in-memory sort")
+	}
+}
+
+func TestMergeFiles(t *testing.T) {
+	dir := t.TempDir()
+	var files []string
+	var expected []string
+	for i := 0; i < 3*mergeBatch+1; i++ {
+		file := filepath.Join(dir, fmt.Sprintf("in%02d.txt", i))
+		if err := os.WriteFile(file, []byte(fmt.Sprintf("%d\n%d\n", i, i+100)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, file)
+		expected = append(expected, fmt.Sprint(i), fmt.Sprint(i+100))
+	}
+	k, _ := ParseKey("1n")
+	opts := Options{Keys: []Key{k}, TempDir: t.TempDir()}
+	expected, _ = SortLines(expected, opts)
+
+	var buf bytes.Buffer
+	if err := MergeFiles(files, &buf, opts); err != nil {
+		t.Fatalf("MergeFiles: %v", err)
+	}
+	if res := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n"); !reflect.DeepEqual(res, expected) {
+		t.Errorf("MergeFiles = %q, expected %q", res, expected)
+	}
+	for _, file := range files {
+		if _, err := os.Stat(file); err != nil {
+			t.Errorf("input file removed: %v", err)
+		}
+	}
+	if left, _ := os.ReadDir(opts.TempDir); len(left) != 0 {
+		t.Errorf("MergeFiles left %d temporary files", len(left))
+	}
+}
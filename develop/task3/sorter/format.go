@@ -0,0 +1,235 @@
+package sorter
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Format - формат записей входа
+type Format int
+
+const (
+	// Text - строки текста, поля разделяются пробелами или Separator
+	Text Format = iota
+	// CSV - записи CSV (RFC 4180), поля разделяются запятой или Separator.
+	// Значения в кавычках могут содержать разделитель и переводы строк.
+	CSV
+	// TSV - записи CSV, поля которых разделяются табуляцией
+	TSV
+	// JSONL - JSON Lines: по одному значению JSON в строке
+	JSONL
+)
+
+var formatNames = []string{"text", "csv", "tsv", "jsonl"}
+
+func (f Format) String() string {
+	if f < 0 || int(f) >= len(formatNames) {
+		return fmt.Sprintf("Format(%d)", int(f))
+	}
+	return formatNames[f]
+}
+
+// ParseFormat возвращает формат по имени: text, csv, tsv или jsonl
+func ParseFormat(s string) (Format, error) {
+	for i, name := range formatNames {
+		if s == name {
+			return Format(i), nil
+		}
+	}
+	return Text, fmt.Errorf("invalid format '%s'", s)
+}
+
+// fieldSep разделяет значения полей записи CSV или JSON в строке, из которой извлекаются ключи
+const fieldSep = "\x00"
+
+// Функция splitCSV - функция разбиения bufio.Scanner на записи CSV:
+// перевод строки внутри кавычек запись не завершает
+func splitCSV(data []byte, atEOF bool) (int, []byte, error) {
+	quoted := false
+	for i, c := range data {
+		switch c {
+		case '"':
+			quoted = !quoted
+		case '\n':
+			if !quoted {
+				return i + 1, data[:i], nil
+			}
+		}
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// Функция validComma проверяет, что r может разделять поля CSV
+func validComma(r rune) bool {
+	return r != 0 && r != '"' && r != '\r' && r != '\n' && utf8.ValidRune(r) && r != utf8.RuneError
+}
+
+// csvFields возвращает значения полей записи CSV без кавычек
+func (s *sorter) csvFields(rec string) []string {
+	r := csv.NewReader(strings.NewReader(strings.TrimSuffix(rec, "\r")))
+	r.Comma = s.comma
+	r.LazyQuotes = true
+	r.FieldsPerRecord = -1
+	fields, _ := r.Read()
+	return fields
+}
+
+// Функция parsePath разбирает путь к полю JSON вида ".user.age".
+// Путь "." обозначает значение целиком.
+func parsePath(name string) ([]string, error) {
+	name = strings.TrimPrefix(name, ".")
+	if name == "" {
+		return nil, nil
+	}
+	path := strings.Split(name, ".")
+	for _, p := range path {
+		if p == "" {
+			return nil, fmt.Errorf("invalid field path '%s'", name)
+		}
+	}
+	return path, nil
+}
+
+// Функция jsonValues возвращает значения полей paths строки JSON line.
+// Отсутствующие поля, null и строки, не являющиеся JSON, дают пустые значения.
+func jsonValues(line string, paths [][]string) []string {
+	res := make([]string, len(paths))
+	dec := json.NewDecoder(strings.NewReader(line))
+	dec.UseNumber()
+	var v any
+	if dec.Decode(&v) != nil {
+		return res
+	}
+	for i, path := range paths {
+		res[i] = jsonText(lookupPath(v, path))
+	}
+	return res
+}
+
+// Функция lookupPath возвращает значение по пути path. Элементы пути,
+// состоящие из цифр, обозначают индекс в массиве.
+func lookupPath(v any, path []string) any {
+	for _, p := range path {
+		switch t := v.(type) {
+		case map[string]any:
+			v = t[p]
+		case []any:
+			i, err := strconv.Atoi(p)
+			if err != nil || i < 0 || i >= len(t) {
+				return nil
+			}
+			v = t[i]
+		default:
+			return nil
+		}
+	}
+	return v
+}
+
+// Функция jsonText возвращает текст значения JSON для сравнения:
+// строки без кавычек, числа в исходной записи, объекты и массивы в компактном виде
+func jsonText(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case json.Number:
+		return t.String()
+	case bool:
+		return fmt.Sprint(t)
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// keyLine возвращает строку, из которой извлекаются ключи записи rec:
+// для CSV и TSV - значения полей, для JSONL - значения полей ключей,
+// разделенные fieldSep. Для текста это сама запись.
+func (s *sorter) keyLine(rec string) string {
+	switch {
+	case s.format == CSV || s.format == TSV:
+		return strings.Join(s.csvFields(rec), fieldSep)
+	case s.format == JSONL && len(s.paths) > 0:
+		return strings.Join(jsonValues(rec, s.paths), fieldSep)
+	}
+	return rec
+}
+
+// setFormat проверяет ключи s.keys для формата записей и готовит их к извлечению
+// из keyLine: ключам по пути JSON назначаются номера полей, ключи по имени столбца
+// получают номера полей при чтении заголовка
+func (s *sorter) setFormat(opts Options) error {
+	s.format, s.header = opts.Format, opts.Header
+	switch s.format {
+	case Text:
+		for _, k := range s.keys {
+			if k.name != "" {
+				return fmt.Errorf("key '%s': field names require csv, tsv or jsonl format", k.name)
+			}
+		}
+	case CSV, TSV:
+		s.comma = ','
+		if s.format == TSV {
+			s.comma = '\t'
+		}
+		if opts.Separator != "" {
+			s.comma, _ = utf8.DecodeRuneInString(opts.Separator)
+		}
+		if !validComma(s.comma) {
+			return fmt.Errorf("invalid field separator %q for %s format", s.comma, s.format)
+		}
+		for _, k := range s.keys {
+			if k.name != "" && !s.header {
+				return fmt.Errorf("key '%s': column names require a header row", k.name)
+			}
+		}
+	case JSONL:
+		for i, k := range opts.Keys {
+			if k.name == "" {
+				return fmt.Errorf("keys of jsonl format must be field paths, e.g. .user.age:n")
+			}
+			path, err := parsePath(k.name)
+			if err != nil {
+				return err
+			}
+			s.paths = append(s.paths, path)
+			s.keys[i].start.field, s.keys[i].end.field = i, i
+		}
+	default:
+		return fmt.Errorf("invalid format %v", s.format)
+	}
+	return nil
+}
+
+// setHeader разбирает заголовок rec и назначает ключам по имени столбца номера полей
+func (s *sorter) setHeader(rec string) error {
+	if s.format != CSV && s.format != TSV {
+		return nil
+	}
+	fields := s.csvFields(rec)
+	for i, k := range s.keys {
+		if k.name == "" {
+			continue
+		}
+		col := -1
+		for j, f := range fields {
+			if f == k.name {
+				col = j
+				break
+			}
+		}
+		if col < 0 {
+			return fmt.Errorf("unknown column '%s'", k.name)
+		}
+		s.keys[i].start.field, s.keys[i].end.field = col, col
+	}
+	return nil
+}
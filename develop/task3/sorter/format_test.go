@@ -0,0 +1,179 @@
+package sorter
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestParseFormat(t *testing.T) {
+	for _, f := range []Format{Text, CSV, TSV, JSONL} {
+		if res, err := ParseFormat(f.String()); err != nil || res != f {
+			t.Errorf("ParseFormat(%q) = %v, %v, expected %v, nil", f.String(), res, err, f)
+		}
+	}
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Error("ParseFormat(\"xml\"): expected error")
+	}
+}
+
+func TestSplitCSV(t *testing.T) {
+	input := "a,\"b\nc\",d\n\"x\"\"\n\",y\r\nlast"
+	expected := []string{"a,\"b\nc\",d", "\"x\"\"\n\",y\r", "last"}
+
+	s := mustSorter(t, Options{Format: CSV})
+	lines, err := s.readLines(s.newScanner(strings.NewReader(input)))
+	if err != nil {
+		t.Fatalf("readLines: %v", err)
+	}
+	if strings.Join(lines, "|") != strings.Join(expected, "|") {
+		t.Errorf("CSV records = %q, expected %q", lines, expected)
+	}
+}
+
+// testSort сортирует input с параметрами opts в памяти и через временные файлы
+// и сравнивает результат с expected
+func testSort(t *testing.T, input string, opts Options, expected string) {
+	t.Helper()
+	for _, size := range []int64{0, 40} {
+		opts.BufferSize = size
+		opts.TempDir = t.TempDir()
+		var buf bytes.Buffer
+		if err := Sort(strings.NewReader(input), &buf, opts); err != nil {
+			t.Fatalf("Sort: %v", err)
+		}
+		if buf.String() != expected {
+			t.Errorf("Sort with BufferSize %d:\n%s\nexpected:\n%s", size, buf.String(), expected)
+		}
+	}
+}
+
+func TestSortCSV(t *testing.T) {
+	input := "name,city,age\n" +
+		"\"Smith, John\",London,42\n" +
+		"Ann,\"New\nYork\",7\n" +
+		"\"Bob \"\"B\"\"\",Paris,19\n"
+
+	age, _ := ParseKey("age:n")
+	testSort(t, input, Options{Format: CSV, Header: true, Keys: []Key{age}},
+		"name,city,age\n"+
+			"Ann,\"New\nYork\",7\n"+
+			"\"Bob \"\"B\"\"\",Paris,19\n"+
+			"\"Smith, John\",London,42\n")
+
+	city, _ := ParseKey("2,2r")
+	testSort(t, input, Options{Format: CSV, Header: true, Keys: []Key{city}},
+		"name,city,age\n"+
+			"\"Bob \"\"B\"\"\",Paris,19\n"+
+			"Ann,\"New\nYork\",7\n"+
+			"\"Smith, John\",London,42\n")
+
+	// без ключей записи сравниваются по значениям полей, а не по тексту с кавычками
+	testSort(t, input, Options{Format: CSV, Header: true},
+		"name,city,age\n"+
+			"Ann,\"New\nYork\",7\n"+
+			"\"Bob \"\"B\"\"\",Paris,19\n"+
+			"\"Smith, John\",London,42\n")
+}
+
+func TestSortTSV(t *testing.T) {
+	input := "id\tscore\n1\t2.5\n2\t10\n3\t-1\n"
+	score, _ := ParseKey("score:nr")
+	testSort(t, input, Options{Format: TSV, Header: true, Keys: []Key{score}},
+		"id\tscore\n2\t10\n1\t2.5\n3\t-1\n")
+
+	// разделитель задается Separator
+	input = "id;score\n1;2.5\n2;10\n"
+	testSort(t, input, Options{Format: CSV, Header: true, Separator: ";", Keys: []Key{score}},
+		"id;score\n2;10\n1;2.5\n")
+}
+
+func TestSortJSONL(t *testing.T) {
+	input := `{"user":{"name":"bob","age":30}}` + "\n" +
+		`{"user":{"name":"ann","age":7},"tags":["x"]}` + "\n" +
+		`{"user":{"name":"eve"}}` + "\n" +
+		`{"user":{"name":"Dan","age":30}}` + "\n"
+
+	age, _ := ParseKey(".user.age:n")
+	name, _ := ParseKey(".user.name:f")
+	testSort(t, input, Options{Format: JSONL, Keys: []Key{age, name}},
+		`{"user":{"name":"eve"}}`+"\n"+
+			`{"user":{"name":"ann","age":7},"tags":["x"]}`+"\n"+
+			`{"user":{"name":"bob","age":30}}`+"\n"+
+			`{"user":{"name":"Dan","age":30}}`+"\n")
+
+	tag, _ := ParseKey(".tags.0")
+	testSort(t, input, Options{Format: JSONL, Keys: []Key{tag}, Reverse: true, Stable: true},
+		`{"user":{"name":"ann","age":7},"tags":["x"]}`+"\n"+
+			`{"user":{"name":"bob","age":30}}`+"\n"+
+			`{"user":{"name":"eve"}}`+"\n"+
+			`{"user":{"name":"Dan","age":30}}`+"\n")
+}
+
+func TestJSONValues(t *testing.T) {
+	line := `{"a":{"b":[1,"x",true,null,{"c":2}]},"n":1.50}`
+	paths := [][]string{{"a", "b", "0"}, {"a", "b", "1"}, {"a", "b", "2"}, {"a", "b", "3"},
+		{"a", "b", "4"}, {"n"}, {"missing"}, {"a", "b", "9"}}
+	expected := []string{"1", "x", "true", "", `{"c":2}`, "1.50", "", ""}
+
+	res := jsonValues(line, paths)
+	if strings.Join(res, "|") != strings.Join(expected, "|") {
+		t.Errorf("jsonValues = %q, expected %q", res, expected)
+	}
+	if res := jsonValues("not json", paths[:1]); res[0] != "" {
+		t.Errorf("jsonValues of invalid JSON = %q, expected empty value", res)
+	}
+}
+
+func TestMergeHeader(t *testing.T) {
+	k, _ := ParseKey("n:n")
+	rs := []io.Reader{
+		strings.NewReader("n,v\n1,a\n3,c\n"),
+		strings.NewReader(""),
+		strings.NewReader("n,v\n2,b\n"),
+	}
+	var buf bytes.Buffer
+	if err := Merge(rs, &buf, Options{Format: CSV, Header: true, Keys: []Key{k}}); err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+	if expected := "n,v\n1,a\n2,b\n3,c\n"; buf.String() != expected {
+		t.Errorf("Merge = %q, expected %q", buf.String(), expected)
+	}
+}
+
+func TestCheckHeader(t *testing.T) {
+	k, _ := ParseKey("n:n")
+	opts := Options{Format: CSV, Header: true, Keys: []Key{k}}
+	if err := Check(strings.NewReader("n\n1\n2\n"), opts); err != nil {
+		t.Errorf("Check = %v, expected nil", err)
+	}
+	err := Check(strings.NewReader("n\n2\n1\n"), opts)
+	if d, ok := err.(*DisorderError); !ok || d.Line != 3 || d.Text != "1" {
+		t.Errorf("Check = %v, expected disorder at line 3", err)
+	}
+}
+
+func TestFormatErrors(t *testing.T) {
+	named, _ := ParseKey("age:n")
+	numbered, _ := ParseKey("2n")
+	bad, _ := ParseKey(".a..b")
+	cases := []Options{
+		{Keys: []Key{named}},
+		{Format: CSV, Keys: []Key{named}},
+		{Format: CSV, Separator: "\""},
+		{Format: JSONL, Keys: []Key{numbered}},
+		{Format: JSONL, Keys: []Key{bad}},
+		{Format: Format(10)},
+	}
+	for _, opts := range cases {
+		if _, err := SortLines(nil, opts); err == nil {
+			t.Errorf("SortLines with %+v: expected error", opts)
+		}
+	}
+
+	_, err := SortLines([]string{"name,city", "a,b"}, Options{Format: CSV, Header: true, Keys: []Key{named}})
+	if err == nil || err.Error() != "unknown column 'age'" {
+		t.Errorf("SortLines with unknown column: error %v", err)
+	}
+}
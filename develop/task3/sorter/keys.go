@@ -0,0 +1,554 @@
+package sorter
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// keyOpts описывает правила сравнения, применяемые к ключу
+type keyOpts struct {
+	numeric bool
+	human   bool
+	month   bool
+	version bool
+	fold    bool
+	dict    bool
+	reverse bool
+}
+
+// check возвращает ошибку, если заданы несовместимые способы сравнения
+func (o keyOpts) check() error {
+	var set string
+	if o.human {
+		set += "h"
+	}
+	if o.month {
+		set += "M"
+	}
+	if o.numeric {
+		set += "n"
+	}
+	if o.version {
+		set += "V"
+	}
+	if len(set) > 1 || o.dict && (o.numeric || o.human) {
+		if o.dict {
+			set = "d" + set
+		}
+		return fmt.Errorf("options '-%s' are incompatible", set)
+	}
+	return nil
+}
+
+// keyPos описывает границу ключа: номер поля (с нуля), смещение в байтах
+// и признак пропуска ведущих пробелов (модификатор b)
+type keyPos struct {
+	field  int
+	char   int
+	blanks bool
+}
+
+// Key описывает ключ сортировки в синтаксисе GNU sort: POS1[,POS2][OPTS],
+// либо поле по имени столбца CSV или пути JSON: NAME[:OPTS].
+// Ключи создаются функцией ParseKey.
+type Key struct {
+	// имя столбца или путь JSON, пустая строка - ключ задан номерами полей
+	name string
+	// для начала ключа char - количество пропускаемых байтов поля,
+	// для конца - номер последнего байта ключа, 0 означает конец поля.
+	// Как в GNU sort, смещения считаются в байтах, а не в символах UTF-8.
+	start     keyPos
+	end       keyPos
+	endOfLine bool
+	opts      keyOpts
+	hasOpts   bool
+	// разделитель полей (-t), пустая строка - переход от пробелов к непробельным символам
+	sep string
+	// правила сравнения строк языка (--locale), nil - побайтовое сравнение
+	coll *collation
+}
+
+var reCount = regexp.MustCompile(`^\d+`)
+
+var errFieldZero = errors.New("field number is zero")
+var errCharZero = errors.New("character offset is zero")
+
+// ParseKey разбирает описание ключа в формате флага -k GNU sort,
+// например "2", "2,3", "2.3,2.5b" или "3nr". Описание, начинающееся не с цифры,
+// задает поле по имени: столбец CSV ("price:n") или путь JSON (".user.age:n").
+func ParseKey(spec string) (k Key, err error) {
+	if spec != "" && (spec[0] < '0' || spec[0] > '9') {
+		return parseNamedKey(spec)
+	}
+	s := spec
+
+	f, s, err := parseCount(s, "invalid number at field start", spec)
+	if err != nil {
+		return k, err
+	}
+	if f == 0 {
+		return k, fmt.Errorf("%v: invalid field specification '%s'", errFieldZero, spec)
+	}
+	k.start.field = f - 1
+	if strings.HasPrefix(s, ".") {
+		var c int
+		c, s, err = parseCount(s[1:], "invalid number after '.'", spec)
+		if err != nil {
+			return k, err
+		}
+		if c == 0 {
+			return k, fmt.Errorf("%v: invalid field specification '%s'", errCharZero, spec)
+		}
+		k.start.char = c - 1
+	}
+	s = k.setOpts(s, &k.start)
+
+	if !strings.HasPrefix(s, ",") {
+		k.endOfLine = true
+	} else {
+		f, s, err = parseCount(s[1:], "invalid number after ','", spec)
+		if err != nil {
+			return k, err
+		}
+		if f == 0 {
+			return k, fmt.Errorf("%v: invalid field specification '%s'", errFieldZero, spec)
+		}
+		k.end.field = f - 1
+		if strings.HasPrefix(s, ".") {
+			k.end.char, s, err = parseCount(s[1:], "invalid number after '.'", spec)
+			if err != nil {
+				return k, err
+			}
+		}
+		s = k.setOpts(s, &k.end)
+	}
+
+	if s != "" {
+		return k, fmt.Errorf("stray character in field spec: invalid field specification '%s'", spec)
+	}
+	return k, k.opts.check()
+}
+
+// Функция parseNamedKey разбирает ключ по имени поля. Модификаторы отделяются
+// последним двоеточием, если после него стоят только модификаторы.
+func parseNamedKey(spec string) (k Key, err error) {
+	k.name = spec
+	if i := strings.LastIndexByte(spec, ':'); i > 0 {
+		var opts Key
+		if rest := opts.setOpts(spec[i+1:], &opts.start); rest == "" {
+			k = opts
+			k.name = spec[:i]
+		}
+	}
+	return k, k.opts.check()
+}
+
+// Функция parseCount отделяет от начала строки s неотрицательное число
+func parseCount(s, msg, spec string) (int, string, error) {
+	d := reCount.FindString(s)
+	if d == "" {
+		return 0, s, fmt.Errorf("%s: invalid count at start of '%s'", msg, s)
+	}
+	n, err := strconv.Atoi(d)
+	if err != nil {
+		return 0, s, fmt.Errorf("%s: invalid count at start of '%s'", msg, spec)
+	}
+	return n, s[len(d):], nil
+}
+
+// setOpts применяет модификаторы из начала строки s к ключу и возвращает остаток строки.
+// Модификатор b относится к границе pos, остальные - к ключу целиком.
+func (k *Key) setOpts(s string, pos *keyPos) string {
+	for ; s != ""; s = s[1:] {
+		switch s[0] {
+		case 'b':
+			pos.blanks = true
+		case 'd':
+			k.opts.dict = true
+		case 'f':
+			k.opts.fold = true
+		case 'h':
+			k.opts.human = true
+		case 'M':
+			k.opts.month = true
+		case 'n':
+			k.opts.numeric = true
+		case 'r':
+			k.opts.reverse = true
+		case 'V':
+			k.opts.version = true
+		default:
+			return s
+		}
+		k.hasOpts = true
+	}
+	return s
+}
+
+// Функция inheritOpts возвращает ключи, в которых ключи без модификаторов получают
+// глобальные правила сравнения и пропуска пробелов из g, как в GNU sort.
+// Разделитель полей и правила языка g получают все ключи.
+// Если ключи не заданы, ключом считается вся строка с правилами g.
+func inheritOpts(ks []Key, g Key) []Key {
+	if len(ks) == 0 {
+		return []Key{g}
+	}
+	res := make([]Key, len(ks))
+	for i, k := range ks {
+		if !k.hasOpts {
+			k.opts = g.opts
+			k.start.blanks = g.start.blanks
+			k.end.blanks = g.end.blanks
+		}
+		k.sep = g.sep
+		k.coll = g.coll
+		res[i] = k
+	}
+	return res
+}
+
+func isBlank(c byte) bool { return c == ' ' || c == '\t' }
+
+// Функция skipBlanks возвращает индекс первого непробельного символа s, начиная с i
+func skipBlanks(s string, i int) int {
+	for i < len(s) && isBlank(s[i]) {
+		i++
+	}
+	return i
+}
+
+// Функция skipField возвращает индекс конца поля, начинающегося с i.
+// Без разделителя ведущие пробелы относятся к полю, как в GNU sort без -t,
+// с разделителем конец поля - позиция ближайшего разделителя.
+func skipField(s string, i int, sep string) int {
+	if sep != "" {
+		if n := strings.Index(s[i:], sep); n >= 0 {
+			return i + n
+		}
+		return len(s)
+	}
+	i = skipBlanks(s, i)
+	for i < len(s) && !isBlank(s[i]) {
+		i++
+	}
+	return i
+}
+
+// Функция skipChars пропускает n байтов строки s, начиная с i
+func skipChars(s string, i, n int) int {
+	if n > len(s)-i {
+		return len(s)
+	}
+	return i + n
+}
+
+// begin возвращает индекс начала ключа в строке s
+func (k Key) begin(s string) int {
+	i := 0
+	for f := 0; f < k.start.field && i < len(s); f++ {
+		i = skipField(s, i, k.sep)
+		if k.sep != "" && i < len(s) {
+			i += len(k.sep)
+		}
+	}
+	if k.start.blanks {
+		i = skipBlanks(s, i)
+	}
+	return skipChars(s, i, k.start.char)
+}
+
+// limit возвращает индекс конца ключа в строке s
+func (k Key) limit(s string) int {
+	if k.endOfLine {
+		return len(s)
+	}
+	fields := k.end.field
+	if k.end.char == 0 {
+		// ключ включает поле целиком
+		fields++
+	}
+	i := 0
+	for f := 0; f < fields && i < len(s); f++ {
+		i = skipField(s, i, k.sep)
+		// разделитель после последнего поля ключа в ключ не входит
+		if k.sep != "" && i < len(s) && (f < fields-1 || k.end.char != 0) {
+			i += len(k.sep)
+		}
+	}
+	if k.end.char != 0 {
+		if k.end.blanks {
+			i = skipBlanks(s, i)
+		}
+		i = skipChars(s, i, k.end.char)
+	}
+	return i
+}
+
+// extract возвращает подстроку s, являющуюся ключом
+func (k Key) extract(s string) string {
+	b, e := k.begin(s), k.limit(s)
+	if e < b {
+		return ""
+	}
+	return s[b:e]
+}
+
+// sortKey - значение ключа строки, вычисляемое один раз перед сортировкой
+type sortKey struct {
+	// текст ключа либо, при сравнении по правилам языка, его ключ сравнения
+	str string
+	// исходный текст ключа при сравнении по правилам языка
+	raw string
+	// значение числа для -h
+	num float64
+	// ранг суффикса для -h или номер месяца для -M
+	rank int
+}
+
+// value вычисляет значение ключа k для строки s
+func (k Key) value(s string) sortKey {
+	key := k.extract(s)
+	switch {
+	case k.opts.numeric:
+		return numValue(key)
+	case k.opts.human:
+		sign, rank, f := parseHuman(key)
+		return sortKey{num: float64(sign) * f, rank: rank}
+	case k.opts.month:
+		return sortKey{rank: monthNum(key)}
+	}
+	key = k.opts.transform(key)
+	if k.coll != nil && !k.opts.version {
+		return sortKey{str: k.coll.key(key), raw: key}
+	}
+	return sortKey{str: key}
+}
+
+// equalKey возвращает строку, одинаковую у значений, равных по ключу k.
+// У неравных значений строки могут совпадать, поэтому при совпадении
+// значения нужно сравнить.
+func (k Key) equalKey(v sortKey) string {
+	switch {
+	case k.opts.numeric:
+		return canonNum(v.str)
+	case k.opts.human:
+		return strconv.FormatFloat(v.num, 'g', -1, 64) + "\x00" + strconv.Itoa(v.rank)
+	case k.opts.month:
+		return strconv.Itoa(v.rank)
+	case k.opts.version:
+		// у равных версий совпадают все символы, кроме цифр
+		return strings.Map(func(r rune) rune {
+			if '0' <= r && r <= '9' {
+				return -1
+			}
+			return r
+		}, v.str)
+	}
+	return v.str + "\x00" + v.raw
+}
+
+// transform применяет к тексту ключа модификаторы d и f
+func (o keyOpts) transform(s string) string {
+	if !o.dict && !o.fold {
+		return s
+	}
+	return strings.Map(func(r rune) rune {
+		if o.dict && !(unicode.IsLetter(r) || unicode.IsDigit(r) || r == ' ' || r == '\t') {
+			return -1
+		}
+		if o.fold {
+			return unicode.ToUpper(r)
+		}
+		return r
+	}, s)
+}
+
+// compareValues сравнивает значения ключа k и возвращает -1, 0 или 1
+func (k Key) compareValues(a, b sortKey) int {
+	var c int
+	switch {
+	case k.opts.numeric:
+		c = cmpNum(a, b)
+	case k.opts.human:
+		c = cmpHuman(a, b)
+	case k.opts.month:
+		c = cmpInt(a.rank, b.rank)
+	case k.opts.version:
+		c = compareVersion(a.str, b.str)
+	default:
+		// строки, равные по правилам языка, упорядочиваются побайтово
+		if c = strings.Compare(a.str, b.str); c == 0 {
+			c = strings.Compare(a.raw, b.raw)
+		}
+	}
+	if k.opts.reverse {
+		return -c
+	}
+	return c
+}
+
+// Функция numPrefix возвращает число в начале s после ведущих пробелов.
+// Как в GNU sort, число состоит из необязательного знака "-", цифр
+// и необязательной дробной части, знак "+" и экспонента не учитываются.
+func numPrefix(s string) string {
+	s = s[skipBlanks(s, 0):]
+	i := 0
+	if i < len(s) && s[i] == '-' {
+		i++
+	}
+	digits := 0
+	for ; i < len(s) && isDigit(s[i]); i++ {
+		digits++
+	}
+	if i < len(s) && s[i] == '.' {
+		i++
+		for ; i < len(s) && isDigit(s[i]); i++ {
+			digits++
+		}
+	}
+	if digits == 0 {
+		return ""
+	}
+	return s[:i]
+}
+
+func isDigit(c byte) bool { return '0' <= c && c <= '9' }
+
+// Функция numValue возвращает значение ключа для сравнения по числу в его начале,
+// ключ без числа равен нулю
+func numValue(s string) sortKey {
+	return sortKey{str: numPrefix(s)}
+}
+
+// Функция cmpNum сравнивает числовые значения ключей. Как в GNU sort, числа
+// сравниваются по цифрам, без перевода в float64, поэтому различаются и числа,
+// отличающиеся за пределами точности float64.
+func cmpNum(a, b sortKey) int {
+	x, y := a.str, b.str
+	xneg, yneg := strings.HasPrefix(x, "-"), strings.HasPrefix(y, "-")
+	x, y = strings.TrimPrefix(x, "-"), strings.TrimPrefix(y, "-")
+	// -0 равен 0
+	xneg, yneg = xneg && !isZeroNum(x), yneg && !isZeroNum(y)
+	switch {
+	case xneg && !yneg:
+		return -1
+	case !xneg && yneg:
+		return 1
+	case xneg:
+		return -cmpAbsNum(x, y)
+	}
+	return cmpAbsNum(x, y)
+}
+
+// Функция cmpAbsNum сравнивает записи неотрицательных чисел:
+// сначала по целой части без ведущих нулей, затем по дробной без конечных
+func cmpAbsNum(a, b string) int {
+	ai, af, _ := strings.Cut(a, ".")
+	bi, bf, _ := strings.Cut(b, ".")
+	ai, bi = strings.TrimLeft(ai, "0"), strings.TrimLeft(bi, "0")
+	if c := cmpInt(len(ai), len(bi)); c != 0 {
+		return c
+	}
+	if c := strings.Compare(ai, bi); c != 0 {
+		return c
+	}
+	return strings.Compare(strings.TrimRight(af, "0"), strings.TrimRight(bf, "0"))
+}
+
+// Функция canonNum возвращает запись числа numPrefix без незначащих нулей,
+// одинаковую у равных чисел
+func canonNum(s string) string {
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+	if isZeroNum(s) {
+		return "0"
+	}
+	i, f, _ := strings.Cut(s, ".")
+	s = strings.TrimLeft(i, "0") + "." + strings.TrimRight(f, "0")
+	if neg {
+		return "-" + s
+	}
+	return s
+}
+
+// Функция isZeroNum сообщает, что запись числа без знака равна нулю
+func isZeroNum(s string) bool {
+	return strings.Trim(s, "0.") == ""
+}
+
+var reHuman = regexp.MustCompile(`^(-?)(\d*\.?\d*)([KMGTPEZYRQk]?)`)
+
+// порядок суффиксов -h: число без суффикса меньше любого числа с суффиксом
+const humanSuffixes = "KMGTPEZYRQ"
+
+// Функция parseHuman возвращает знак числа в начале s, ранг его суффикса и абсолютное значение
+func parseHuman(s string) (sign, rank int, f float64) {
+	m := reHuman.FindStringSubmatch(s[skipBlanks(s, 0):])
+	f, err := strconv.ParseFloat(m[2], 64)
+	if err != nil || f == 0 {
+		return 0, 0, 0
+	}
+	sign = 1
+	if m[1] == "-" {
+		sign = -1
+	}
+	if m[3] != "" {
+		rank = strings.Index(humanSuffixes, strings.ToUpper(m[3])) + 1
+	}
+	return sign, rank, f
+}
+
+// Функция cmpHuman сравнивает значения ключей -h: сначала по знаку,
+// затем по суффиксу и только затем по значению
+func cmpHuman(a, b sortKey) int {
+	as, bs := cmpFloat(a.num, 0), cmpFloat(b.num, 0)
+	switch {
+	case as != bs:
+		return cmpInt(as, bs)
+	case a.rank != b.rank:
+		return as * cmpInt(a.rank, b.rank)
+	}
+	return cmpFloat(a.num, b.num)
+}
+
+var months = []string{"JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}
+
+// Функция monthNum возвращает номер месяца, с сокращения которого начинается s,
+// без учета регистра и ведущих пробелов, и 0, если s не начинается с месяца
+func monthNum(s string) int {
+	s = s[skipBlanks(s, 0):]
+	if len(s) < 3 {
+		return 0
+	}
+	prefix := strings.ToUpper(s[:3])
+	for i, m := range months {
+		if prefix == m {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+func cmpFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func cmpInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,276 @@
+package sorter
+
+import (
+	"testing"
+)
+
+func TestParseKeySpec(t *testing.T) {
+	k, err := ParseKey("2.3b,4.5nr")
+	if err != nil {
+		t.Fatalf("ParseKey: %v", err)
+	}
+	expected := Key{
+		start:   keyPos{field: 1, char: 2, blanks: true},
+		end:     keyPos{field: 3, char: 5},
+		opts:    keyOpts{numeric: true, reverse: true},
+		hasOpts: true,
+	}
+	if k != expected {
+		t.Errorf("ParseKey(\"2.3b,4.5nr\") = %+v, expected %+v", k, expected)
+	}
+
+	k, err = ParseKey("3")
+	if err != nil || !k.endOfLine || k.start.field != 2 || k.hasOpts {
+		t.Errorf("ParseKey(\"3\") = %+v, %v, expected key from field 3 to end of line", k, err)
+	}
+}
+
+func TestParseKeySpecIncorrect(t *testing.T) {
+	for _, s := range []string{"", "0", "1.0", "a:nM", "1,", "1.", "2x", "1,2,3"} {
+		if _, err := ParseKey(s); err == nil {
+			t.Errorf("ParseKey(%q): expected error", s)
+		}
+	}
+}
+
+func TestParseNamedKey(t *testing.T) {
+	cases := []struct {
+		spec     string
+		expected Key
+	}{
+		{"price", Key{name: "price"}},
+		{".user.age:n", Key{name: ".user.age", opts: keyOpts{numeric: true}, hasOpts: true}},
+		{"name:bfr", Key{name: "name", start: keyPos{blanks: true}, opts: keyOpts{fold: true, reverse: true}, hasOpts: true}},
+		{"time:stamp", Key{name: "time:stamp"}},
+	}
+	for _, c := range cases {
+		if k, err := ParseKey(c.spec); err != nil || k != c.expected {
+			t.Errorf("ParseKey(%q) = %+v, %v, expected %+v", c.spec, k, err, c.expected)
+		}
+	}
+}
+
+func TestKeyExtract(t *testing.T) {
+	line := "ab  cdef\tgh"
+	cases := []struct {
+		spec     string
+		expected string
+	}{
+		{"1", line},
+		{"2", "  cdef\tgh"},
+		{"2,2", "  cdef"},
+		{"2b,2", "cdef"},
+		{"2.2,2.4", " cd"},
+		{"2.2b,2.4b", "def"},
+		{"2,2.0", "  cdef"},
+		{"3,2", ""},
+		{"5", ""},
+	}
+	for _, c := range cases {
+		k, err := ParseKey(c.spec)
+		if err != nil {
+			t.Fatalf("ParseKey(%q): %v", c.spec, err)
+		}
+		if res := k.extract(line); res != c.expected {
+			t.Errorf("key %q of %q = %q, expected %q", c.spec, line, res, c.expected)
+		}
+	}
+}
+
+func TestKeyExtractBytes(t *testing.T) {
+	// как в GNU sort, смещения считаются в байтах, а не в символах
+	k, _ := ParseKey("1.3,1.4")
+	if res := k.extract("мама мыла"); res != "а" {
+		t.Errorf("key \"1.3,1.4\" of \"мама мыла\" = %q, expected \"а\"", res)
+	}
+	k, _ = ParseKey("1.2,1.2")
+	if res := k.extract("мама"); res != "\xbc" {
+		t.Errorf("key \"1.2,1.2\" of \"мама\" = %q, expected \"\\xbc\"", res)
+	}
+}
+
+func TestInheritOpts(t *testing.T) {
+	plain, _ := ParseKey("1,1")
+	own, _ := ParseKey("2,2r")
+	g := Key{
+		start:     keyPos{blanks: true},
+		end:       keyPos{blanks: true},
+		endOfLine: true,
+		opts:      keyOpts{numeric: true},
+	}
+
+	ks := inheritOpts([]Key{plain, own}, g)
+	if ks[0].opts != g.opts || !ks[0].start.blanks || !ks[0].end.blanks {
+		t.Errorf("key without options = %+v, expected options %+v and skipping blanks", ks[0], g.opts)
+	}
+	if ks[1].opts != (keyOpts{reverse: true}) || ks[1].start.blanks {
+		t.Errorf("key with options = %+v, expected only reverse", ks[1])
+	}
+
+	ks = inheritOpts(nil, g)
+	if len(ks) != 1 || ks[0] != g {
+		t.Errorf("inheritOpts(nil) = %+v, expected whole line key", ks)
+	}
+}
+
+// compareKey сравнивает строки a и b по ключу spec, как при сортировке
+func compareKey(t *testing.T, spec, a, b string) int {
+	t.Helper()
+	k, err := ParseKey(spec)
+	if err != nil {
+		t.Fatalf("ParseKey(%q): %v", spec, err)
+	}
+	return k.compareValues(k.value(a), k.value(b))
+}
+
+func TestCompareNum(t *testing.T) {
+	cases := []struct {
+		a, b     string
+		expected int
+	}{
+		{" 10", "9", 1},
+		{"2abc", "2", 0},
+		{"x", "-5", 1},
+		{"x", "0", 0},
+		{"x", "1", -1},
+		{"1e2", "99", -1},
+		{"+5", "3", -1},
+		{"9007199254740993", "9007199254740992", 1},
+		{"-9007199254740993", "-9007199254740992", -1},
+		{"007.50", "7.5", 0},
+		{"0.05", ".5", -1},
+		{"-0", "", 0},
+	}
+	for _, c := range cases {
+		if res := compareKey(t, "1n", c.a, c.b); res != c.expected {
+			t.Errorf("key 1n of %q and %q = %d, expected %d", c.a, c.b, res, c.expected)
+		}
+	}
+}
+
+func TestEqualKey(t *testing.T) {
+	// у равных по ключу значений equalKey совпадает
+	cases := []struct {
+		spec, a, b string
+	}{
+		{"1n", "007.50", "7.5x"},
+		{"1n", "-0", "abc"},
+		{"1h", "1.5K", " 1.50k"},
+		{"1M", "jan", "January"},
+		{"1V", "file01.txt", "file1.txt"},
+		{"1f", "Abc", "aBC"},
+	}
+	for _, c := range cases {
+		k, _ := ParseKey(c.spec)
+		va, vb := k.value(c.a), k.value(c.b)
+		if k.compareValues(va, vb) != 0 {
+			t.Fatalf("key %s of %q and %q: values are not equal", c.spec, c.a, c.b)
+		}
+		if ea, eb := k.equalKey(va), k.equalKey(vb); ea != eb {
+			t.Errorf("key %s: equalKey(%q) = %q, equalKey(%q) = %q, expected equal", c.spec, c.a, ea, c.b, eb)
+		}
+	}
+}
+
+func TestParseKeySpecIncompatible(t *testing.T) {
+	if _, err := ParseKey("2nM"); err == nil {
+		t.Error("ParseKey(\"2nM\"): expected error")
+	}
+}
+
+func TestCompareHuman(t *testing.T) {
+	cases := []struct {
+		a, b     string
+		expected int
+	}{
+		{"2K", "1M", -1},
+		{"1M", "3G", -1},
+		{"1500K", "1M", -1},
+		{"10", "1K", -1},
+		{" 2k", "1K", 1},
+		{"-1G", "-1K", -1},
+		{"-1", "abc", -1},
+		{"1.5M", "1.5M", 0},
+		{"+5", "0", 0},
+		{"+5K", "3", -1},
+	}
+	for _, c := range cases {
+		if res := compareKey(t, "1h", c.a, c.b); res != c.expected {
+			t.Errorf("key 1h of %q and %q = %d, expected %d", c.a, c.b, res, c.expected)
+		}
+	}
+}
+
+func TestCompareMonth(t *testing.T) {
+	cases := []struct {
+		a, b     string
+		expected int
+	}{
+		{"January", "March", -1},
+		{" dec", "nov", 1},
+		{"Jan", "JANUARY", 0},
+		{"foo", "jan", -1},
+	}
+	for _, c := range cases {
+		if res := compareKey(t, "1M", c.a, c.b); res != c.expected {
+			t.Errorf("key 1M of %q and %q = %d, expected %d", c.a, c.b, res, c.expected)
+		}
+	}
+}
+
+func TestKeyExtractSeparator(t *testing.T) {
+	line := "a:: b:cd:e"
+	cases := []struct {
+		spec     string
+		expected string
+	}{
+		{"1,1", "a"},
+		{"2,2", ""},
+		{"3,3", " b"},
+		{"3b,3", "b"},
+		{"2,3", ": b"},
+		{"3.2,4.1", "b:c"},
+		{"4", "cd:e"},
+		{"6", ""},
+	}
+	for _, c := range cases {
+		k, err := ParseKey(c.spec)
+		if err != nil {
+			t.Fatalf("ParseKey(%q): %v", c.spec, err)
+		}
+		k.sep = ":"
+		if res := k.extract(line); res != c.expected {
+			t.Errorf("key %q of %q with -t: = %q, expected %q", c.spec, line, res, c.expected)
+		}
+	}
+}
+
+func TestKeyTransform(t *testing.T) {
+	cases := []struct {
+		opts     keyOpts
+		s        string
+		expected string
+	}{
+		{keyOpts{}, "Ёж-1, a", "Ёж-1, a"},
+		{keyOpts{fold: true}, "ёж-1, a", "ЁЖ-1, A"},
+		{keyOpts{dict: true}, "Ёж-1,\ta!", "Ёж1\ta"},
+		{keyOpts{dict: true, fold: true}, "ёж-1, a", "ЁЖ1 A"},
+	}
+	for _, c := range cases {
+		if res := c.opts.transform(c.s); res != c.expected {
+			t.Errorf("%+v.transform(%q) = %q, expected %q", c.opts, c.s, res, c.expected)
+		}
+	}
+}
+
+func TestParseKeySpecVersion(t *testing.T) {
+	k, err := ParseKey("2Vf")
+	if err != nil || !k.opts.version || !k.opts.fold {
+		t.Errorf("ParseKey(\"2Vf\") = %+v, %v, expected version and fold options", k, err)
+	}
+	for _, s := range []string{"2Vn", "2dn", "2hM"} {
+		if _, err := ParseKey(s); err == nil {
+			t.Errorf("ParseKey(%q): expected error", s)
+		}
+	}
+}
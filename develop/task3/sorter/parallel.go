@@ -0,0 +1,99 @@
+package sorter
+
+import (
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// parallelMinLines - минимальное число строк, которое имеет смысл сортировать в отдельной горутине
+const parallelMinLines = 1 << 13
+
+// DefaultParallel возвращает число горутин сортировки по умолчанию:
+// число процессоров, но не больше 8, как в GNU sort
+func DefaultParallel() int {
+	return minInt(runtime.NumCPU(), 8)
+}
+
+// Функция partitions делит n элементов на не более чем parts частей
+// и возвращает границы частей
+func partitions(n, parts int) []int {
+	parts = minInt(parts, n/parallelMinLines)
+	if parts < 1 {
+		parts = 1
+	}
+	bounds := make([]int, parts+1)
+	for i := range bounds {
+		bounds[i] = n * i / parts
+	}
+	return bounds
+}
+
+// sortRecords вычисляет ключи строк и возвращает записи, устойчиво
+// отсортированные с использованием до s.parallel горутин
+func (s *sorter) sortRecords(lines []string) []record {
+	bounds := partitions(len(lines), s.parallel)
+	recs := make([]record, len(lines))
+
+	var wg sync.WaitGroup
+	for p := 0; p < len(bounds)-1; p++ {
+		wg.Add(1)
+		go func(part []record, lines []string) {
+			defer wg.Done()
+			for i, line := range lines {
+				part[i] = s.makeRecord(line)
+			}
+			sort.SliceStable(part, func(i, j int) bool {
+				return s.compare(&part[i], &part[j]) < 0
+			})
+		}(recs[bounds[p]:bounds[p+1]], lines[bounds[p]:bounds[p+1]])
+	}
+	wg.Wait()
+
+	return s.mergeParts(recs, bounds)
+}
+
+// mergeParts попарно сливает отсортированные части recs с границами bounds,
+// сливая пары каждого уровня параллельно, и возвращает отсортированный слайс
+func (s *sorter) mergeParts(recs []record, bounds []int) []record {
+	buf := make([]record, len(recs))
+	for len(bounds) > 2 {
+		var next []int
+		var wg sync.WaitGroup
+		for p := 0; p < len(bounds)-1; p += 2 {
+			next = append(next, bounds[p])
+			if p+2 >= len(bounds) {
+				// нечетная часть переносится без слияния
+				copy(buf[bounds[p]:], recs[bounds[p]:bounds[p+1]])
+				continue
+			}
+			wg.Add(1)
+			go func(lo, mid, hi int) {
+				defer wg.Done()
+				s.mergeRecords(buf[lo:hi], recs[lo:mid], recs[mid:hi])
+			}(bounds[p], bounds[p+1], bounds[p+2])
+		}
+		wg.Wait()
+		bounds = append(next, len(recs))
+		recs, buf = buf, recs
+	}
+	return recs
+}
+
+// mergeRecords сливает отсортированные a и b в dst.
+// При равенстве первой идет запись из a, поэтому слияние устойчиво.
+func (s *sorter) mergeRecords(dst, a, b []record) {
+	i, j, k := 0, 0, 0
+	for i < len(a) && j < len(b) {
+		if s.compare(&b[j], &a[i]) < 0 {
+			dst[k] = b[j]
+			j++
+		} else {
+			dst[k] = a[i]
+			i++
+		}
+		k++
+	}
+	k += copy(dst[k:], a[i:])
+	copy(dst[k:], b[j:])
+}
@@ -0,0 +1,83 @@
+package sorter
+
+import (
+	"math/rand"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestPartitions(t *testing.T) {
+	cases := []struct {
+		n, parts int
+		expected []int
+	}{
+		{10, 4, []int{0, 10}},
+		{0, 4, []int{0, 0}},
+		{3 * parallelMinLines, 2, []int{0, 3 * parallelMinLines / 2, 3 * parallelMinLines}},
+		{3 * parallelMinLines, 8, []int{0, parallelMinLines, 2 * parallelMinLines, 3 * parallelMinLines}},
+	}
+	for _, c := range cases {
+		if res := partitions(c.n, c.parts); !reflect.DeepEqual(res, c.expected) {
+			t.Errorf("partitions(%d, %d) = %v, expected %v", c.n, c.parts, res, c.expected)
+		}
+	}
+}
+
+// randomLines возвращает n строк вида "число слово"
+func randomLines(n int) []string {
+	r := rand.New(rand.NewSource(1))
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = strconv.Itoa(r.Intn(1000000)-500000) + " w" + strconv.Itoa(r.Intn(100))
+	}
+	return lines
+}
+
+// Функция recordLines возвращает строки записей recs
+func recordLines(recs []record) []string {
+	lines := make([]string, len(recs))
+	for i := range recs {
+		lines[i] = recs[i].line
+	}
+	return lines
+}
+
+func TestSortRecordsParallel(t *testing.T) {
+	k, _ := ParseKey("1,1n")
+	s := mustSorter(t, Options{Keys: []Key{k}})
+
+	// 7 частей: слияние с нечетным числом частей на каждом уровне
+	lines := randomLines(7 * parallelMinLines)
+
+	s.parallel = 1
+	seq := s.sortRecords(lines)
+	s.parallel = 7
+	par := s.sortRecords(lines)
+
+	if !reflect.DeepEqual(recordLines(seq), recordLines(par)) {
+		t.Fatal("result of parallel sortRecords is differ from sequential")
+	}
+	for i := 1; i < len(par); i++ {
+		if s.compare(&par[i-1], &par[i]) > 0 {
+			t.Fatalf("lines %d and %d are out of order: %q, %q", i-1, i, par[i-1].line, par[i].line)
+		}
+	}
+}
+
+func BenchmarkSortRecordsNumeric(b *testing.B) {
+	k, _ := ParseKey("1,1n")
+	lines := randomLines(1 << 18)
+
+	for _, p := range []int{1, 4} {
+		b.Run("parallel="+strconv.Itoa(p), func(b *testing.B) {
+			s, err := newSorter(Options{Keys: []Key{k}, Parallel: p})
+			if err != nil {
+				b.Fatal(err)
+			}
+			for i := 0; i < b.N; i++ {
+				s.sortRecords(lines)
+			}
+		})
+	}
+}
@@ -0,0 +1,441 @@
+// Package sorter сортирует строки по правилам утилиты sort из GNU coreutils:
+// ключи -k, числовое, месячное и версионное сравнение, устойчивая сортировка,
+// удаление повторов, внешняя сортировка больших входов и слияние отсортированных входов.
+package sorter
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Options описывает параметры сортировки. Нулевое значение соответствует
+// запуску sort без флагов: побайтовое сравнение строк целиком.
+type Options struct {
+	// Keys - ключи сортировки (-k), при пустом списке ключом является вся строка
+	Keys []Key
+
+	// Глобальные правила сравнения применяются к ключам без собственных модификаторов,
+	// как в GNU sort
+	IgnoreLeadingBlanks bool // -b
+	Dictionary          bool // -d
+	FoldCase            bool // -f
+	Human               bool // -h
+	Month               bool // -M
+	Numeric             bool // -n
+	Version             bool // -V
+	Reverse             bool // -r
+
+	// Stable отключает последнее сравнение строк целиком при равенстве ключей (-s)
+	Stable bool
+	// Unique оставляет из строк, равных по ключам с учетом правил сравнения,
+	// первую во входе (-u), последнее сравнение строк целиком при этом не выполняется
+	Unique bool
+	// Count, как Unique, оставляет одну строку из равных и выводит перед ней
+	// число равных строк, как sort | uniq -c (--count)
+	Count bool
+	// Duplicates, как Unique, оставляет одну строку из равных, но выводит
+	// только повторяющиеся строки (--duplicates)
+	Duplicates bool
+
+	// Top выводит только Top наибольших строк, начиная с наибольшей (--top),
+	// а Bottom - только Bottom наименьших, начиная с наименьшей (--bottom).
+	// Из равных строк выбираются более ранние во входе. Вход не сортируется
+	// и не хранится целиком: в памяти находятся только отобранные строки.
+	Top    int
+	Bottom int
+
+	// Separator - разделитель полей из одного символа (-t), по умолчанию поля
+	// разделяются переходом от пробелов к непробельным символам,
+	// а в форматах CSV и TSV - запятой и табуляцией
+	Separator string
+	// Locale - язык правил сравнения строк, например "ru" или "en",
+	// по умолчанию строки сравниваются побайтово
+	Locale string
+
+	// BufferSize - объем памяти под строки в байтах, при превышении которого
+	// отсортированные части сохраняются во временные файлы (-S), 0 - без ограничения
+	BufferSize int64
+	// TempDir - каталог временных файлов (-T), по умолчанию os.TempDir()
+	TempDir string
+	// Parallel - число горутин сортировки (--parallel), 0 - по числу процессоров, но не больше 8
+	Parallel int
+
+	// Format - формат записей. В форматах CSV, TSV и JSONL ключи извлекаются из значений
+	// полей, а записи выводятся в исходном виде, включая кавычки.
+	Format Format
+	// Header оставляет первую запись входа (первую запись каждого входа при слиянии)
+	// в начале вывода. В форматах CSV и TSV заголовок задает имена столбцов для ключей.
+	Header bool
+}
+
+// sorter хранит проверенные параметры сортировки
+type sorter struct {
+	keys    []Key
+	reverse bool
+	stable  bool
+	// unique - объединять равные строки (Unique, Count или Duplicates)
+	unique   bool
+	count    bool
+	dups     bool
+	top      int
+	bottom   int
+	limit    int64
+	dir      string
+	parallel int
+
+	format Format
+	header bool
+	// разделитель полей CSV и TSV
+	comma rune
+	// пути JSON ключей формата JSONL
+	paths [][]string
+}
+
+// Функция newSorter проверяет параметры и вычисляет ключи с учетом глобальных правил
+func newSorter(opts Options) (*sorter, error) {
+	if opts.Parallel < 0 {
+		return nil, fmt.Errorf("invalid number of threads: %d", opts.Parallel)
+	}
+	if opts.Top < 0 || opts.Bottom < 0 {
+		return nil, fmt.Errorf("invalid number of lines: %d", minInt(opts.Top, opts.Bottom))
+	}
+	if opts.Top > 0 && opts.Bottom > 0 {
+		return nil, errors.New("top and bottom are mutually exclusive")
+	}
+	if (opts.Top > 0 || opts.Bottom > 0) && (opts.Count || opts.Duplicates) {
+		return nil, errors.New("top and bottom are incompatible with count and duplicates")
+	}
+	if opts.BufferSize < 0 {
+		return nil, fmt.Errorf("invalid buffer size: %d", opts.BufferSize)
+	}
+	if utf8.RuneCountInString(opts.Separator) > 1 {
+		return nil, fmt.Errorf("multi-character tab '%s'", opts.Separator)
+	}
+
+	g := Key{
+		start:     keyPos{blanks: opts.IgnoreLeadingBlanks},
+		end:       keyPos{blanks: opts.IgnoreLeadingBlanks},
+		endOfLine: true,
+		opts: keyOpts{
+			numeric: opts.Numeric,
+			human:   opts.Human,
+			month:   opts.Month,
+			version: opts.Version,
+			fold:    opts.FoldCase,
+			dict:    opts.Dictionary,
+			reverse: opts.Reverse,
+		},
+		sep: opts.Separator,
+	}
+	if opts.Format != Text {
+		g.sep = fieldSep
+	}
+	if err := g.opts.check(); err != nil {
+		return nil, err
+	}
+	if opts.Locale != "" {
+		var err error
+		if g.coll, err = newCollation(opts.Locale); err != nil {
+			return nil, fmt.Errorf("invalid locale '%s': %v", opts.Locale, err)
+		}
+	}
+
+	s := &sorter{
+		keys:     inheritOpts(opts.Keys, g),
+		reverse:  opts.Reverse,
+		stable:   opts.Stable,
+		unique:   opts.Unique || opts.Count || opts.Duplicates,
+		count:    opts.Count,
+		dups:     opts.Duplicates,
+		top:      opts.Top,
+		bottom:   opts.Bottom,
+		limit:    opts.BufferSize,
+		dir:      opts.TempDir,
+		parallel: opts.Parallel,
+	}
+	if s.parallel == 0 {
+		s.parallel = DefaultParallel()
+	}
+	if err := s.setFormat(opts); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Sort читает строки из r, сортирует их и записывает результат в w.
+// С BufferSize больше нуля объем памяти под строки ограничен,
+// а не поместившиеся части сортируются через временные файлы.
+func Sort(r io.Reader, w io.Writer, opts Options) error {
+	s, err := newSorter(opts)
+	if err != nil {
+		return err
+	}
+	sc := s.newScanner(r)
+	// заголовок выводится только после чтения всего входа, так как w может
+	// оказаться входным файлом, создаваемым заново при первой записи (sort -o)
+	hw := &headerWriter{w: w}
+	if s.header && sc.Scan() {
+		if err := s.setHeader(sc.Text()); err != nil {
+			return err
+		}
+		hw.header = sc.Text() + "\n"
+	}
+	if err := s.sortScanner(sc, hw); err != nil {
+		return err
+	}
+	return hw.writeHeader()
+}
+
+// sortScanner сортирует записи сканера и записывает результат в w.
+// В w ничего не записывается, пока вход не прочитан целиком.
+func (s *sorter) sortScanner(sc *bufio.Scanner, w io.Writer) error {
+	if s.top > 0 || s.bottom > 0 {
+		recs, err := s.selectTop(sc)
+		if err != nil {
+			return err
+		}
+		return s.writeRecords(w, recs, true)
+	}
+	if s.limit > 0 {
+		return s.sortExternal(sc, w)
+	}
+	lines, err := s.readLines(sc)
+	if err != nil {
+		return err
+	}
+	return s.writeRecords(w, s.sortRecords(lines), true)
+}
+
+// headerWriter записывает в w заголовок перед первой записью
+type headerWriter struct {
+	w io.Writer
+	// header - еще не записанный заголовок с переводом строки
+	header string
+}
+
+func (h *headerWriter) Write(p []byte) (int, error) {
+	if err := h.writeHeader(); err != nil {
+		return 0, err
+	}
+	return h.w.Write(p)
+}
+
+// writeHeader записывает заголовок, если он еще не записан
+func (h *headerWriter) writeHeader() error {
+	if h.header == "" {
+		return nil
+	}
+	_, err := io.WriteString(h.w, h.header)
+	h.header = ""
+	return err
+}
+
+// SortLines сортирует строки (записи) в памяти и возвращает строки,
+// которые Sort записал бы в вывод, включая число повторов с Count.
+func SortLines(lines []string, opts Options) ([]string, error) {
+	s, err := newSorter(opts)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]string, 0, len(lines))
+	if s.header && len(lines) > 0 {
+		if err := s.setHeader(lines[0]); err != nil {
+			return nil, err
+		}
+		res, lines = append(res, lines[0]), lines[1:]
+	}
+	g := s.newGroupWriter(func(line string) error {
+		res = append(res, line)
+		return nil
+	}, true)
+	var recs []record
+	if s.top > 0 || s.bottom > 0 {
+		h := s.newTopHeap()
+		for i, line := range lines {
+			h.add(line, i)
+		}
+		recs = h.sorted()
+	} else {
+		recs = s.sortRecords(lines)
+	}
+	for _, rec := range recs {
+		g.write(rec)
+	}
+	g.flush()
+	return res, nil
+}
+
+// errMergeTop - ошибка слияния с Top или Bottom
+var errMergeTop = errors.New("top and bottom are not supported when merging")
+
+// Merge сливает уже отсортированные входы rs и записывает результат в w, не сортируя их заново
+func Merge(rs []io.Reader, w io.Writer, opts Options) error {
+	s, err := newSorter(opts)
+	if err != nil {
+		return err
+	}
+	if s.top > 0 || s.bottom > 0 {
+		return errMergeTop
+	}
+	return s.mergeReaders(rs, w, s.header, true)
+}
+
+// MergeFiles сливает уже отсортированные файлы. Имя "-" означает Stdin.
+// Если файлов больше, чем можно открыть за один проход, они предварительно
+// сливаются группами во временные файлы в каталоге TempDir.
+func MergeFiles(files []string, w io.Writer, opts Options) error {
+	s, err := newSorter(opts)
+	if err != nil {
+		return err
+	}
+	if s.top > 0 || s.bottom > 0 {
+		return errMergeTop
+	}
+	return s.mergeFiles(files, w, s.header)
+}
+
+// DisorderError описывает первую строку, нарушающую порядок сортировки
+type DisorderError struct {
+	// Line - номер строки (записи), начиная с 1
+	Line int
+	Text string
+}
+
+func (e *DisorderError) Error() string {
+	return fmt.Sprintf("%d: disorder: %s", e.Line, e.Text)
+}
+
+// Check проверяет, что строки r отсортированы, и возвращает *DisorderError
+// для первой строки, нарушающей порядок. С Unique равные по ключам соседние
+// строки также считаются нарушением порядка.
+func Check(r io.Reader, opts Options) error {
+	s, err := newSorter(opts)
+	if err != nil {
+		return err
+	}
+
+	scanner := s.newScanner(r)
+	first := 1
+	if s.header && scanner.Scan() {
+		if err := s.setHeader(scanner.Text()); err != nil {
+			return err
+		}
+		first = 2
+	}
+	var prev record
+	for n := first; scanner.Scan(); n++ {
+		cur := s.makeRecord(scanner.Text())
+		if n > first {
+			disorder := false
+			if s.unique {
+				disorder = s.compareKeys(&prev, &cur) >= 0
+			} else {
+				disorder = s.compare(&prev, &cur) > 0
+			}
+			if disorder {
+				return &DisorderError{n, cur.line}
+			}
+		}
+		prev = cur
+	}
+	return scanner.Err()
+}
+
+// record - строка с предвычисленными значениями ключей,
+// чтобы при сортировке не разбирать строку при каждом сравнении
+type record struct {
+	line string
+	keys []sortKey
+	// ключ сравнения строки целиком по правилам языка
+	lineKey string
+}
+
+// makeRecord вычисляет значения ключей строки
+func (s *sorter) makeRecord(line string) record {
+	r := record{line: line, keys: make([]sortKey, len(s.keys))}
+	kl := s.keyLine(line)
+	for i, k := range s.keys {
+		r.keys[i] = k.value(kl)
+	}
+	if s.keys[0].coll != nil {
+		r.lineKey = s.keys[0].coll.key(line)
+	}
+	return r
+}
+
+// compareKeys сравнивает записи только по ключам
+func (s *sorter) compareKeys(a, b *record) int {
+	for i, k := range s.keys {
+		if c := k.compareValues(a.keys[i], b.keys[i]); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// compare сравнивает записи по ключам, а при равенстве ключей, как GNU sort,
+// сравнивает строки целиком по правилам языка и побайтово (с учетом Reverse).
+// Со Stable и Unique последнее сравнение не выполняется. Возвращает -1, 0 или 1.
+func (s *sorter) compare(a, b *record) int {
+	if c := s.compareKeys(a, b); c != 0 || s.stable || s.unique {
+		return c
+	}
+	c := strings.Compare(a.lineKey, b.lineKey)
+	if c == 0 {
+		c = strings.Compare(a.line, b.line)
+	}
+	if s.reverse {
+		return -c
+	}
+	return c
+}
+
+// readLines читает строки сканера
+func (s *sorter) readLines(scanner *bufio.Scanner) (lines []string, err error) {
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err()
+}
+
+// maxLineSize - максимальная длина строки входа
+const maxLineSize = 1 << 30
+
+// Функция newLineScanner возвращает сканер, разбивающий вход на строки только по '\n',
+// чтобы строки выводились байт в байт, включая '\r' в конце строк
+func newLineScanner(r io.Reader) *bufio.Scanner {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), maxLineSize)
+	sc.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			return i + 1, data[:i], nil
+		}
+		if atEOF && len(data) > 0 {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	})
+	return sc
+}
+
+// newScanner возвращает сканер, разбивающий вход на записи формата s.format
+func (s *sorter) newScanner(r io.Reader) *bufio.Scanner {
+	sc := newLineScanner(r)
+	if s.format == CSV || s.format == TSV {
+		sc.Split(splitCSV)
+	}
+	return sc
+}
+
+// Функция minInt возвращает меньшее из двух целых чисел
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
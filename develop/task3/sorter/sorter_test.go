@@ -0,0 +1,212 @@
+package sorter
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// mustSorter возвращает sorter с параметрами opts и завершает тест при ошибке
+func mustSorter(t testing.TB, opts Options) *sorter {
+	t.Helper()
+	s, err := newSorter(opts)
+	if err != nil {
+		t.Fatalf("newSorter: %v", err)
+	}
+	return s
+}
+
+// readFile читает строки файла с параметрами s
+func readFile(t *testing.T, s *sorter, file string) []string {
+	t.Helper()
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	lines, err := s.readLines(s.newScanner(f))
+	if err != nil {
+		t.Fatalf("readLines: %v", err)
+	}
+	return lines
+}
+
+func TestSortUnique(t *testing.T) {
+	lines := readFile(t, mustSorter(t, Options{}), "test.txt")
+	key2, _ := ParseKey("2,2")
+	key2f, _ := ParseKey("2,2f")
+	gnu := []string{"2 b", "1 B", "3 b", "01 x", "1 y"}
+
+	// результаты получены командой LC_ALL=C sort (GNU coreutils 9.1)
+	cases := []struct {
+		lines    []string
+		opts     Options
+		expected []string
+	}{
+		{lines, Options{Unique: true}, []string{"11 8 0", "6 7 3", "6 7 3 5 cat 12", "January 8 dog", "March 8 -1"}},
+		{lines, Options{Keys: []Key{key2}, Unique: true}, []string{"6 7 3", "January 8 dog"}},
+		{lines, Options{Keys: []Key{key2}, Unique: true, Reverse: true}, []string{"January 8 dog", "6 7 3"}},
+		{gnu, Options{Numeric: true, Unique: true}, []string{"1 B", "2 b", "3 b"}},
+		{gnu, Options{Keys: []Key{key2f}, Unique: true}, []string{"2 b", "01 x", "1 y"}},
+		{gnu, Options{Keys: []Key{key2f}, Unique: true, Reverse: true}, []string{"2 b", "01 x", "1 y"}},
+	}
+	for _, c := range cases {
+		res, err := SortLines(c.lines, c.opts)
+		if err != nil {
+			t.Fatalf("SortLines: %v", err)
+		}
+		if !reflect.DeepEqual(res, c.expected) {
+			t.Errorf("SortLines(%q) with %+v = %q, expected %q", c.lines, c.opts, res, c.expected)
+		}
+	}
+}
+
+func TestSortCount(t *testing.T) {
+	lines := []string{"b", "a", "B", "c", "b", "a", "b"}
+	cases := []struct {
+		opts     Options
+		expected []string
+	}{
+		{Options{Count: true}, []string{"      1 B", "      2 a", "      3 b", "      1 c"}},
+		{Options{Count: true, FoldCase: true}, []string{"      2 a", "      4 b", "      1 c"}},
+		{Options{Duplicates: true}, []string{"a", "b"}},
+		{Options{Duplicates: true, Count: true, Reverse: true}, []string{"      3 b", "      2 a"}},
+	}
+	for _, c := range cases {
+		res, err := SortLines(lines, c.opts)
+		if err != nil {
+			t.Fatalf("SortLines: %v", err)
+		}
+		if !reflect.DeepEqual(res, c.expected) {
+			t.Errorf("SortLines(%q) with %+v = %q, expected %q", lines, c.opts, res, c.expected)
+		}
+	}
+}
+
+func TestCompareLinesTieBreak(t *testing.T) {
+	k1, _ := ParseKey("2,2n")
+	k2, _ := ParseKey("1,1r")
+	s := mustSorter(t, Options{Keys: []Key{k1, k2}})
+
+	lines := recordLines(s.sortRecords([]string{"a 10", "b 2", "c 2", "d x"}))
+
+	expected := []string{"d x", "c 2", "b 2", "a 10"}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("sorted lines = %q, expected %q", lines, expected)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	f, err := os.Open("test.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	err = Check(f, Options{})
+	expected := &DisorderError{3, "11 8 0"}
+	if !reflect.DeepEqual(err, expected) {
+		t.Errorf("Check = %v, expected %v", err, expected)
+	}
+}
+
+func TestCheckUnique(t *testing.T) {
+	input := "1 Jan\n2 Feb\n2 feb\n"
+	k, _ := ParseKey("2M")
+	opts := Options{Keys: []Key{k}}
+
+	if err := Check(strings.NewReader(input), opts); err != nil {
+		t.Errorf("Check(%q) = %v, expected nil", input, err)
+	}
+
+	opts.Unique = true
+	err := Check(strings.NewReader(input), opts)
+	expected := &DisorderError{3, "2 feb"}
+	if !reflect.DeepEqual(err, expected) {
+		t.Errorf("Check(%q) with Unique = %v, expected %v", input, err, expected)
+	}
+}
+
+func TestReadLinesPreservesLines(t *testing.T) {
+	long := strings.Repeat("x", 100*1024)
+	input := "b\t 2\r\n  a  1\n" + long + "\n\nlast"
+	s := mustSorter(t, Options{})
+
+	expected := []string{"b\t 2\r", "  a  1", long, "", "last"}
+
+	lines, err := s.readLines(s.newScanner(strings.NewReader(input)))
+	if err != nil {
+		t.Fatalf("readLines: %v", err)
+	}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Fatalf("readLines(%q) = %q, expected %q", input, lines, expected)
+	}
+}
+
+func TestSort(t *testing.T) {
+	k, _ := ParseKey("2,2n")
+	input := "b 10\na 2\nc 2\nd -1"
+	expected := "d -1\na 2\nc 2\nb 10\n"
+
+	for _, size := range []int64{0, 8} {
+		var buf bytes.Buffer
+		opts := Options{Keys: []Key{k}, BufferSize: size, TempDir: t.TempDir()}
+		if err := Sort(strings.NewReader(input), &buf, opts); err != nil {
+			t.Fatalf("Sort: %v", err)
+		}
+		if buf.String() != expected {
+			t.Errorf("Sort(%q) with BufferSize %d = %q, expected %q", input, size, buf.String(), expected)
+		}
+	}
+}
+
+func TestSortLines(t *testing.T) {
+	lines := []string{"b", "c", "a", "B", "a"}
+	res, err := SortLines(lines, Options{Unique: true, Reverse: true})
+	if err != nil {
+		t.Fatalf("SortLines: %v", err)
+	}
+
+	expected := []string{"c", "b", "a", "B"}
+	if !reflect.DeepEqual(res, expected) {
+		t.Errorf("SortLines(%q) = %q, expected %q", lines, res, expected)
+	}
+	if lines[0] != "b" {
+		t.Error("SortLines modified its argument")
+	}
+}
+
+func TestMerge(t *testing.T) {
+	rs := []io.Reader{
+		strings.NewReader("1 a\n3 a\n"),
+		strings.NewReader("2 b\n3 b\n4 b"),
+	}
+	k, _ := ParseKey("1,1n")
+
+	var buf bytes.Buffer
+	if err := Merge(rs, &buf, Options{Keys: []Key{k}, Stable: true}); err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+	expected := "1 a\n2 b\n3 a\n3 b\n4 b\n"
+	if buf.String() != expected {
+		t.Errorf("Merge = %q, expected %q", buf.String(), expected)
+	}
+}
+
+func TestOptionsInvalid(t *testing.T) {
+	cases := []Options{
+		{Parallel: -1},
+		{BufferSize: -1},
+		{Separator: "::"},
+		{Numeric: true, Month: true},
+		{Locale: "not a locale!"},
+	}
+	for _, opts := range cases {
+		if _, err := SortLines(nil, opts); err == nil {
+			t.Errorf("SortLines with %+v: expected error", opts)
+		}
+	}
+}
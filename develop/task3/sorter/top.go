@@ -0,0 +1,139 @@
+package sorter
+
+import (
+	"bufio"
+	"container/heap"
+	"strings"
+)
+
+// ranked - запись с номером во входе для отбора лучших записей
+type ranked struct {
+	rec record
+	idx int
+	// key - equalKey записи при Unique
+	key string
+}
+
+// topHeap хранит не более n лучших записей входа. В корне находится худшая
+// из них, чтобы ее можно было заменить лучшей записью за O(log n).
+// Лучшими считаются наибольшие записи при top и наименьшие при bottom,
+// при равенстве - более ранние во входе.
+type topHeap struct {
+	s     *sorter
+	top   bool
+	n     int
+	items []ranked
+	// seen - записи кучи по equalKey при Unique, чтобы найти равную запись,
+	// не просматривая кучу целиком
+	seen map[string][]ranked
+}
+
+// newTopHeap возвращает пустую кучу для отбора Top или Bottom записей
+func (s *sorter) newTopHeap() *topHeap {
+	h := &topHeap{s: s, top: s.top > 0, n: s.top + s.bottom}
+	if s.unique {
+		h.seen = make(map[string][]ranked)
+	}
+	return h
+}
+
+// equalKey возвращает строку, одинаковую у записей, равных по всем ключам
+func (s *sorter) equalKey(r *record) string {
+	var b strings.Builder
+	for i, k := range s.keys {
+		b.WriteString(k.equalKey(r.keys[i]))
+		b.WriteByte(0)
+	}
+	return b.String()
+}
+
+// better сообщает, что запись a лучше записи b
+func (h *topHeap) better(a, b *ranked) bool {
+	c := h.s.compare(&a.rec, &b.rec)
+	if h.top {
+		c = -c
+	}
+	return c < 0 || c == 0 && a.idx < b.idx
+}
+
+func (h *topHeap) Len() int           { return len(h.items) }
+func (h *topHeap) Less(i, j int) bool { return h.better(&h.items[j], &h.items[i]) }
+func (h *topHeap) Swap(i, j int)      { h.items[i], h.items[j] = h.items[j], h.items[i] }
+func (h *topHeap) Push(x any)         { h.items = append(h.items, x.(ranked)) }
+func (h *topHeap) Pop() any {
+	r := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return r
+}
+
+// contains сообщает, есть ли в куче запись, равная r по ключам
+func (h *topHeap) contains(r *ranked) bool {
+	same := h.seen[r.key]
+	for i := range same {
+		if h.s.compareKeys(&same[i].rec, &r.rec) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// forget удаляет вытесненную из кучи запись r из seen
+func (h *topHeap) forget(r *ranked) {
+	same := h.seen[r.key]
+	for i := range same {
+		if same[i].idx == r.idx {
+			same = append(same[:i], same[i+1:]...)
+			break
+		}
+	}
+	if len(same) == 0 {
+		delete(h.seen, r.key)
+	} else {
+		h.seen[r.key] = same
+	}
+}
+
+// add добавляет запись line с номером idx во входе, если она входит в число лучших
+func (h *topHeap) add(line string, idx int) {
+	r := ranked{rec: h.s.makeRecord(line), idx: idx}
+	if len(h.items) == h.n && !h.better(&r, &h.items[0]) {
+		return
+	}
+	// равная по ключам запись, встреченная раньше, лучше r;
+	// если она уже вытеснена из кучи, то r тем более в нее не входит
+	if h.seen != nil {
+		r.key = h.s.equalKey(&r.rec)
+		if h.contains(&r) {
+			return
+		}
+		h.seen[r.key] = append(h.seen[r.key], r)
+	}
+	if len(h.items) < h.n {
+		heap.Push(h, r)
+		return
+	}
+	if h.seen != nil {
+		h.forget(&h.items[0])
+	}
+	h.items[0] = r
+	heap.Fix(h, 0)
+}
+
+// sorted возвращает отобранные записи, начиная с лучшей
+func (h *topHeap) sorted() []record {
+	recs := make([]record, h.Len())
+	for i := len(recs) - 1; i >= 0; i-- {
+		recs[i] = heap.Pop(h).(ranked).rec
+	}
+	return recs
+}
+
+// selectTop читает записи сканера и возвращает лучшие из них, начиная с лучшей,
+// храня в памяти только отобранные записи
+func (s *sorter) selectTop(scanner *bufio.Scanner) ([]record, error) {
+	h := s.newTopHeap()
+	for idx := 0; scanner.Scan(); idx++ {
+		h.add(scanner.Text(), idx)
+	}
+	return h.sorted(), scanner.Err()
+}
@@ -0,0 +1,117 @@
+package sorter
+
+import (
+	"bytes"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// expectedTop возвращает n лучших строк, полученных полной устойчивой сортировкой
+func expectedTop(t *testing.T, lines []string, opts Options, n int) []string {
+	t.Helper()
+	s := mustSorter(t, opts)
+	recs := make([]record, len(lines))
+	for i, line := range lines {
+		recs[i] = s.makeRecord(line)
+	}
+	sort.SliceStable(recs, func(i, j int) bool {
+		c := s.compare(&recs[i], &recs[j])
+		if opts.Top > 0 {
+			return c > 0
+		}
+		return c < 0
+	})
+
+	var res []string
+	var last *record
+	for i := range recs {
+		if len(res) == n {
+			break
+		}
+		if s.unique && last != nil && s.compareKeys(last, &recs[i]) == 0 {
+			continue
+		}
+		res, last = append(res, recs[i].line), &recs[i]
+	}
+	return res
+}
+
+func TestSortLinesTop(t *testing.T) {
+	lines := randomLines(2000)
+	key1n, _ := ParseKey("1,1n")
+	key2, _ := ParseKey("2,2")
+	cases := []Options{
+		{Top: 10, Keys: []Key{key1n}},
+		{Bottom: 10, Keys: []Key{key1n}},
+		{Top: 25, Keys: []Key{key2}},
+		{Top: 25, Keys: []Key{key2}, Stable: true},
+		{Bottom: 7, Keys: []Key{key2}, Unique: true},
+		{Top: 7, Keys: []Key{key2}, Unique: true},
+		{Top: 300, Keys: []Key{key2, key1n}, Unique: true},
+		{Bottom: 50, Keys: []Key{key1n}, Unique: true, Reverse: true},
+		{Top: 5, Keys: []Key{key2}, Reverse: true},
+		{Bottom: 3000, Keys: []Key{key2, key1n}},
+	}
+	for _, opts := range cases {
+		res, err := SortLines(lines, opts)
+		if err != nil {
+			t.Fatalf("SortLines: %v", err)
+		}
+		expected := expectedTop(t, lines, opts, opts.Top+opts.Bottom)
+		if !reflect.DeepEqual(res, expected) {
+			t.Errorf("SortLines with %+v = %q, expected %q", opts, res, expected)
+		}
+	}
+}
+
+func TestSortTop(t *testing.T) {
+	input := "size\n10 a\n7 b\n12 c\n10 d\n1 e\n"
+	k, _ := ParseKey("1,1n")
+	cases := []struct {
+		opts     Options
+		expected string
+	}{
+		{Options{Top: 2, Keys: []Key{k}}, "12 c\n10 d\n"},
+		{Options{Top: 3, Keys: []Key{k}, Stable: true}, "12 c\n10 a\n10 d\n"},
+		{Options{Bottom: 2, Keys: []Key{k}, Header: true}, "size\n1 e\n7 b\n"},
+		{Options{Top: 10, Keys: []Key{k}, Header: true, Unique: true}, "size\n12 c\n10 a\n7 b\n1 e\n"},
+	}
+	for _, c := range cases {
+		var buf bytes.Buffer
+		if err := Sort(strings.NewReader(input), &buf, c.opts); err != nil {
+			t.Fatalf("Sort: %v", err)
+		}
+		if buf.String() != c.expected {
+			t.Errorf("Sort with %+v = %q, expected %q", c.opts, buf.String(), c.expected)
+		}
+	}
+}
+
+func TestTopErrors(t *testing.T) {
+	for _, opts := range []Options{{Top: -1}, {Top: 1, Bottom: 1}, {Bottom: 1, Count: true}} {
+		if _, err := SortLines(nil, opts); err == nil {
+			t.Errorf("SortLines with %+v: expected error", opts)
+		}
+	}
+	if err := Merge(nil, &bytes.Buffer{}, Options{Top: 1}); err == nil {
+		t.Error("Merge with Top: expected error")
+	}
+}
+
+func BenchmarkTop(b *testing.B) {
+	k, _ := ParseKey("1,1n")
+	lines := randomLines(1 << 18)
+	s, err := newSorter(Options{Keys: []Key{k}, Top: 10})
+	if err != nil {
+		b.Fatal(err)
+	}
+	for i := 0; i < b.N; i++ {
+		h := s.newTopHeap()
+		for idx, line := range lines {
+			h.add(line, idx)
+		}
+		h.sorted()
+	}
+}
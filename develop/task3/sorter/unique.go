@@ -0,0 +1,87 @@
+package sorter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// groupWriter выводит отсортированные записи. С Unique, Count и Duplicates
+// из каждой группы соседних равных по ключам записей выводится только первая,
+// как в GNU sort -u: сортировка устойчива, поэтому это первая запись группы во входе.
+type groupWriter struct {
+	s    *sorter
+	emit func(line string) error
+	// group - объединять группы, count и dups - выводить число записей группы
+	// и только группы из нескольких записей
+	group, count, dups bool
+
+	first record
+	n     int
+}
+
+// newGroupWriter возвращает groupWriter, передающий выводимые строки в emit.
+// Промежуточные части внешней сортировки (final = false) должны сохранить
+// все записи групп, чтобы их можно было посчитать при окончательном слиянии.
+func (s *sorter) newGroupWriter(emit func(string) error, final bool) *groupWriter {
+	g := &groupWriter{s: s, emit: emit, group: s.unique}
+	if final {
+		g.count, g.dups = s.count, s.dups
+	} else if s.count || s.dups {
+		g.group = false
+	}
+	return g
+}
+
+// Функция lineWriter возвращает буферизованный w и функцию, записывающую в него строку
+func lineWriter(w io.Writer) (*bufio.Writer, func(string) error) {
+	bw := bufio.NewWriter(w)
+	return bw, func(line string) error {
+		bw.WriteString(line)
+		return bw.WriteByte('\n')
+	}
+}
+
+// write добавляет очередную запись
+func (g *groupWriter) write(rec record) error {
+	if !g.group {
+		return g.emit(rec.line)
+	}
+	if g.n > 0 && g.s.compareKeys(&g.first, &rec) == 0 {
+		g.n++
+		return nil
+	}
+	if err := g.flush(); err != nil {
+		return err
+	}
+	g.first, g.n = rec, 1
+	return nil
+}
+
+// flush выводит накопленную группу
+func (g *groupWriter) flush() error {
+	n := g.n
+	g.n = 0
+	if n == 0 || g.dups && n < 2 {
+		return nil
+	}
+	if g.count {
+		return g.emit(fmt.Sprintf("%7d %s", n, g.first.line))
+	}
+	return g.emit(g.first.line)
+}
+
+// writeRecords записывает отсортированные записи в w
+func (s *sorter) writeRecords(w io.Writer, recs []record, final bool) error {
+	bw, emit := lineWriter(w)
+	g := s.newGroupWriter(emit, final)
+	for i := range recs {
+		if err := g.write(recs[i]); err != nil {
+			return err
+		}
+	}
+	if err := g.flush(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,112 @@
+package sorter
+
+// Функция verOrder возвращает вес символа s[pos] при сравнении версий:
+// конец строки и '~' меньше всего, затем цифры, буквы и прочие символы
+func verOrder(s string, pos int) int {
+	if pos == len(s) {
+		return -1
+	}
+	c := s[pos]
+	switch {
+	case isDigit(c):
+		return 0
+	case isAlpha(c):
+		return int(c)
+	case c == '~':
+		return -2
+	}
+	return int(c) + 256
+}
+
+func isAlpha(c byte) bool { return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' }
+
+// Функция verrevcmp сравнивает строки как версии Debian: нечисловые части
+// сравниваются посимвольно, числовые - как числа
+func verrevcmp(a, b string) int {
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		firstDiff := 0
+		for i < len(a) && !isDigit(a[i]) || j < len(b) && !isDigit(b[j]) {
+			ac, bc := verOrder(a, i), verOrder(b, j)
+			if ac != bc {
+				return ac - bc
+			}
+			i++
+			j++
+		}
+		for i < len(a) && a[i] == '0' {
+			i++
+		}
+		for j < len(b) && b[j] == '0' {
+			j++
+		}
+		for i < len(a) && j < len(b) && isDigit(a[i]) && isDigit(b[j]) {
+			if firstDiff == 0 {
+				firstDiff = int(a[i]) - int(b[j])
+			}
+			i++
+			j++
+		}
+		if i < len(a) && isDigit(a[i]) {
+			return 1
+		}
+		if j < len(b) && isDigit(b[j]) {
+			return -1
+		}
+		if firstDiff != 0 {
+			return firstDiff
+		}
+	}
+	return 0
+}
+
+// Функция filePrefixLen возвращает длину имени файла без суффиксов вида ".tar.gz"
+func filePrefixLen(s string) int {
+	prefix := 0
+	for i := 0; i < len(s); {
+		i++
+		prefix = i
+		for i+1 < len(s) && s[i] == '.' && (isAlpha(s[i+1]) || s[i+1] == '~') {
+			for i += 2; i < len(s) && (isAlpha(s[i]) || isDigit(s[i]) || s[i] == '~'); i++ {
+			}
+		}
+	}
+	return prefix
+}
+
+// Функция compareVersion сравнивает строки как имена файлов с номерами версий
+// (file2 < file10, 1.2.9 < 1.2.10), по алгоритму filevercmp из GNU coreutils
+func compareVersion(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return -1
+	case b == "":
+		return 1
+	}
+
+	// ".", затем "..", затем скрытые файлы, затем остальные
+	if a[0] == '.' {
+		if b[0] != '.' {
+			return -1
+		}
+		for _, special := range []string{".", ".."} {
+			switch {
+			case a == special:
+				return -1
+			case b == special:
+				return 1
+			}
+		}
+	} else if b[0] == '.' {
+		return 1
+	}
+
+	ap, bp := filePrefixLen(a), filePrefixLen(b)
+	c := verrevcmp(a[:ap], b[:bp])
+	if c == 0 && (ap != len(a) || bp != len(b)) {
+		c = verrevcmp(a, b)
+	}
+	return cmpInt(c, 0)
+}
@@ -0,0 +1,34 @@
+package sorter
+
+import (
+	"testing"
+)
+
+func TestCompareVersion(t *testing.T) {
+	cases := []struct {
+		a, b     string
+		expected int
+	}{
+		{"file2", "file10", -1},
+		{"file10.txt", "file2.txt", 1},
+		{"1.2.9", "1.2.10", -1},
+		{"1.0~rc1", "1.0", -1},
+		{"file01", "file1", 0},
+		{"file1.txt", "file1.tar.gz", 1},
+		{".hidden", "a", -1},
+		{".", "..", -1},
+		{"..", ".a", -1},
+		{"", "a", -1},
+		{"abc", "abc", 0},
+		{"a~", "a", -1},
+		{"ab", "a1", 1},
+	}
+	for _, c := range cases {
+		if res := compareVersion(c.a, c.b); res != c.expected {
+			t.Errorf("compareVersion(%q, %q) = %d, expected %d", c.a, c.b, res, c.expected)
+		}
+		if res := compareVersion(c.b, c.a); res != -c.expected {
+			t.Errorf("compareVersion(%q, %q) = %d, expected %d", c.b, c.a, res, -c.expected)
+		}
+	}
+}
@@ -1,15 +1,16 @@
 package main
 
 import (
-	"bufio"
+	"errors"
 	"flag"
 	"fmt"
-	"math"
+	"io"
 	"os"
-	"sort"
-	"strconv"
 	"strings"
 	"testing"
+	"unicode/utf8"
+
+	"go-sort/sorter"
 )
 
 /*
@@ -37,261 +38,308 @@ import (
 Программа должна проходить все тесты. Код должен проходить проверки go vet и golint.
 */
 
-var key string
+var keys keyList
 var numeric bool
 var reverse bool
 var unique bool
+var month bool
+var blanks bool
+var check bool
+var human bool
+var bufferSize string
+var tempDir string
+var parallel int
+var tab string
+var stable bool
+var fold bool
+var dict bool
+var version bool
+var locale string
+var merge bool
+var output string
+var files0From string
+var format string
+var header bool
+var count bool
+var duplicates bool
+var top int
+var bottom int
 
 func init() {
 	testing.Init()
-	flag.StringVar(&key, "k", "0", "sort via a key column")
+	flag.Var(&keys, "k", "sort via a key; KEYDEF is F[.C][OPTS][,F[.C][OPTS]], may be repeated")
 	flag.BoolVar(&numeric, "n", false, "compare according to string numerical value")
 	flag.BoolVar(&reverse, "r", false, "reverse the result of comparisons")
 	flag.BoolVar(&unique, "u", false, "output only the first of an equal run")
-	flag.Parse()
-}
-
-// имплементация sort.Interface для лексикографической сортировки
-type stringSort struct {
-	sl  [][]string
-	key int
+	flag.BoolVar(&month, "M", false, "compare (unknown) < 'JAN' < ... < 'DEC'")
+	flag.BoolVar(&blanks, "b", false, "ignore leading blanks")
+	flag.BoolVar(&count, "count", false, "output one line of each equal run prefixed by its size, like sort | uniq -c")
+	flag.BoolVar(&duplicates, "duplicates", false, "output only the first line of each equal run of several lines")
+	flag.IntVar(&top, "top", 0, "output only the N greatest lines, greatest first, without sorting the whole input")
+	flag.IntVar(&bottom, "bottom", 0, "output only the N least lines, least first, without sorting the whole input")
+	flag.BoolVar(&check, "c", false, "check for sorted input; do not sort")
+	flag.BoolVar(&human, "h", false, "compare human readable numbers (e.g., 2K 1G)")
+	flag.StringVar(&bufferSize, "S", "", "use SIZE for main memory buffer and sort larger input via temporary files")
+	flag.StringVar(&tempDir, "T", "", "use DIR for temporaries, not $TMPDIR")
+	flag.BoolVar(&fold, "f", false, "fold lower case to upper case characters")
+	flag.BoolVar(&dict, "d", false, "consider only blanks and alphanumeric characters")
+	flag.BoolVar(&version, "V", false, "natural sort of (version) numbers within text")
+	flag.StringVar(&locale, "locale", "", "compare strings using collation rules of LANG (e.g. ru, en)")
+	flag.BoolVar(&merge, "m", false, "merge already sorted files; do not sort")
+	flag.StringVar(&output, "o", "", "write result to FILE instead of standard output")
+	flag.StringVar(&files0From, "files0-from", "", "read input from the files specified by NUL-terminated names in file F")
+	flag.BoolVar(&stable, "s", false, "stabilize sort by disabling last-resort comparison")
+	flag.StringVar(&tab, "t", "", "use SEP instead of non-blank to blank transition")
+	flag.StringVar(&format, "format", "text", "input format: text, csv, tsv or jsonl; -k may name a CSV column or a JSON field path")
+	flag.BoolVar(&header, "header", false, "keep the first line on top; in csv and tsv formats it names the columns")
+	flag.IntVar(&parallel, "parallel", sorter.DefaultParallel(), "change the number of sorts run concurrently to N")
+	if err := flag.CommandLine.Parse(expandArgs(os.Args[1:])); err != nil {
+		os.Exit(2)
+	}
 }
 
-func (s stringSort) Len() int      { return len(s.sl) }
-func (s stringSort) Swap(i, j int) { s.sl[i], s.sl[j] = s.sl[j], s.sl[i] }
-func (s stringSort) Less(i, j int) bool {
-	iLen := len(s.sl[i])
-	jLen := len(s.sl[j])
-	iHasKey := iLen > s.key
-	jHasKey := jLen > s.key
-
-	// сравнение по элементу key и случай, когда в одном из слайсов элементов меньше, чем key
-	switch {
-	case iHasKey && jHasKey:
-		if s.sl[i][s.key] < s.sl[j][s.key] {
-			return true
+// Функция expandArgs приводит короткие флаги в стиле GNU к виду, понятному пакету flag:
+// "-nr" превращается в "-n -r", а "-k2,2n" - в "-k 2,2n".
+func expandArgs(args []string) []string {
+	res := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" || len(arg) < 2 || arg[0] != '-' {
+			return append(res, args[i:]...)
 		}
-		if s.sl[i][s.key] > s.sl[j][s.key] {
-			return false
+		name := strings.TrimLeft(arg, "-")
+		hasValue := false
+		if n := strings.IndexByte(name, '='); n >= 0 {
+			name, hasValue = name[:n], true
 		}
-	case !iHasKey && jHasKey:
-		return true
-	case iHasKey && !jHasKey:
-		return false
-	}
-
-	// элементы слайсов с индексом key равны либо key лежит вне длин обоих слайсов
-	for k := 0; k < minInt(iLen, jLen); k++ {
-		if s.sl[i][k] < s.sl[j][k] {
-			return true
+		var expanded []string
+		if f := flag.Lookup(name); f != nil || strings.HasPrefix(arg, "--") {
+			expanded = []string{arg}
+			if f == nil || isBoolFlag(f) {
+				hasValue = true
+			}
+		} else {
+			expanded, hasValue = splitShort(arg[1:])
 		}
-		if s.sl[i][k] > s.sl[j][k] {
-			return false
+		res = append(res, expanded...)
+		// значение флага передано следующим аргументом
+		if !hasValue && i+1 < len(args) {
+			i++
+			res = append(res, args[i])
 		}
 	}
-
-	// слайсы равны по элементам, индексы которых есть в обоих слайсах
-	return iLen < jLen
-}
-
-// имплементация sort.Interface для сортировки чисел
-type numericSort struct {
-	sl  [][]string
-	key int
+	return res
 }
 
-func (s numericSort) Len() int      { return len(s.sl) }
-func (s numericSort) Swap(i, j int) { s.sl[i], s.sl[j] = s.sl[j], s.sl[i] }
-func (s numericSort) Less(i, j int) bool {
-	iLen := len(s.sl[i])
-	jLen := len(s.sl[j])
-	iHasKey := iLen > s.key
-	jHasKey := jLen > s.key
-
-	// сравнение по элементу key и случай, когда в одном из слайсов элементов меньше, чем key
-	switch {
-	case iHasKey && jHasKey:
-		if lessNum(s.sl[i][s.key], s.sl[j][s.key]) {
-			return true
-		}
-		if lessNum(s.sl[j][s.key], s.sl[i][s.key]) {
-			return false
+// Функция splitShort разбивает группу коротких флагов без ведущего "-".
+// Первый флаг со значением забирает остаток группы в качестве значения.
+// Возвращает false, если значение последнего флага нужно взять из следующего аргумента.
+func splitShort(s string) ([]string, bool) {
+	var res []string
+	for i := 0; i < len(s); i++ {
+		f := flag.Lookup(s[i : i+1])
+		if f == nil {
+			// неизвестный флаг: пусть об ошибке сообщит пакет flag
+			return append(res, "-"+s[i:]), true
 		}
-	case !iHasKey && jHasKey:
-		return true
-	case iHasKey && !jHasKey:
-		return false
-	}
-
-	// элементы слайсов с индексом key равны либо key лежит вне длин обоих слайсов
-	for k := 0; k < minInt(iLen, jLen); k++ {
-		if lessNum(s.sl[i][k], s.sl[j][k]) {
-			return true
+		res = append(res, "-"+s[i:i+1])
+		if isBoolFlag(f) {
+			continue
 		}
-		if lessNum(s.sl[j][k], s.sl[i][k]) {
-			return false
+		if i+1 < len(s) {
+			return append(res, s[i+1:]), true
 		}
+		return res, false
 	}
+	return res, true
+}
 
-	// слайсы равны по элементам, индексы которых есть в обоих слайсах
-	return iLen < jLen
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
 }
 
-// Функция lessNum сравнивает строки как числа и возвращает true,
-// если i < j либо j является числом, а i - нет.
-func lessNum(i, j string) bool {
-	iFloat, iIsNum := stringToFloat(i)
-	jFloat, jIsNum := stringToFloat(j)
-	if jIsNum && (!iIsNum || iFloat < jFloat) {
-		return true
+// keyList реализует flag.Value и накапливает ключи, переданные несколькими флагами -k
+type keyList []sorter.Key
+
+func (l *keyList) String() string { return fmt.Sprint(len(*l)) }
+
+func (l *keyList) Set(s string) error {
+	k, err := sorter.ParseKey(s)
+	if err != nil {
+		return err
 	}
-	return false
+	*l = append(*l, k)
+	return nil
 }
 
-// Функция minInt возвращает меньшее из двух целых чисел
-func minInt(a, b int) int {
-	if a < b {
-		return a
+// Функция parseTab проверяет разделитель полей, переданный флагом -t.
+// Как в GNU sort, "\0" означает нулевой байт.
+func parseTab(s string) (string, error) {
+	if s == `\0` {
+		return "\x00", nil
 	}
-	return b
+	if utf8.RuneCountInString(s) > 1 {
+		return "", fmt.Errorf("multi-character tab '%s'", s)
+	}
+	return s, nil
 }
 
-// Функция stringToFloat конвертирует строку в число и возвращает его,
-// а также идикатор успешного выполнения.
-// Причем stringToFloat("NaN") = 0, false.
-func stringToFloat(s string) (float64, bool) {
-	f, err := strconv.ParseFloat(s, 64)
-	if err != nil || math.IsNaN(f) {
-		return 0, false
+// Функция optionsFromFlags возвращает параметры сортировки, заданные флагами
+func optionsFromFlags() (sorter.Options, error) {
+	opts := sorter.Options{
+		Keys:                keys,
+		IgnoreLeadingBlanks: blanks,
+		Dictionary:          dict,
+		FoldCase:            fold,
+		Human:               human,
+		Month:               month,
+		Numeric:             numeric,
+		Version:             version,
+		Reverse:             reverse,
+		Stable:              stable,
+		Unique:              unique,
+		Count:               count,
+		Duplicates:          duplicates,
+		Top:                 top,
+		Bottom:              bottom,
+		Locale:              locale,
+		TempDir:             tempDir,
+		Parallel:            parallel,
+		Header:              header,
+	}
+	if parallel < 1 {
+		return opts, fmt.Errorf("invalid number of threads: %d", parallel)
 	}
-	return f, true
-}
 
-func readStrings(file string) (lines []string, err error) {
-	var scanner *bufio.Scanner
-	if file == "" {
-		scanner = bufio.NewScanner(os.Stdin)
-	} else {
-		f, err := os.Open(file)
-		if err != nil {
-			return nil, err
+	var err error
+	if opts.Separator, err = parseTab(tab); err != nil {
+		return opts, err
+	}
+	if opts.Format, err = sorter.ParseFormat(format); err != nil {
+		return opts, err
+	}
+	// с флагом -S объем памяти под строки ограничен, остальное сортируется через временные файлы
+	if bufferSize != "" {
+		if opts.BufferSize, err = sorter.ParseSize(bufferSize); err != nil {
+			return opts, err
 		}
-		scanner = bufio.NewScanner(f)
-		defer f.Close()
 	}
+	return opts, nil
+}
 
-	if unique {
-		uLines := make(map[string]struct{})
-		for scanner.Scan() {
-			line := scanner.Text()
-			if _, ok := uLines[line]; !ok {
-				uLines[line] = struct{}{}
-				lines = append(lines, line)
-			}
-		}
-	} else {
-		for scanner.Scan() {
-			lines = append(lines, scanner.Text())
+// Функция sortFiles сортирует строки файлов и записывает результат в w.
+// С флагом -m файлы считаются отсортированными и только сливаются.
+func sortFiles(files []string, w io.Writer, opts sorter.Options) error {
+	if merge {
+		if len(files) == 0 {
+			files = []string{"-"}
 		}
+		return sorter.MergeFiles(files, w, opts)
 	}
 
-	return lines, nil
+	f := openFiles(files)
+	defer f.Close()
+	return sorter.Sort(f, w, opts)
 }
 
-func readStringSlices(file string, k int) (lines [][]string, err error) {
-	var scanner *bufio.Scanner
-	if file == "" {
-		scanner = bufio.NewScanner(os.Stdin)
-	} else {
-		f, err := os.Open(file)
-		if err != nil {
-			return nil, err
-		}
-		scanner = bufio.NewScanner(f)
-		defer f.Close()
+// Функция checkSorted проверяет, что строки файла отсортированы,
+// и дополняет сообщение о нарушении порядка именем файла
+func checkSorted(file string, opts sorter.Options) error {
+	f, err := openFile(file)
+	if err != nil {
+		return err
 	}
+	defer f.Close()
 
-	if unique {
-		uLines := make(map[string]struct{})
-		for scanner.Scan() {
-			line := strings.Fields(scanner.Text())
-			var field string
-			if k < len(line) {
-				field = line[k]
-			}
-			if _, ok := uLines[field]; !ok {
-				uLines[field] = struct{}{}
-				lines = append(lines, line)
-			}
-		}
-	} else {
-		for scanner.Scan() {
-			lineString := scanner.Text()
-			lines = append(lines, strings.Fields(lineString))
-		}
+	if file == "" {
+		file = "-"
 	}
-
-	return lines, nil
+	if err := sorter.Check(f, opts); err != nil {
+		return fmt.Errorf("%s:%w", file, err)
+	}
+	return nil
 }
 
-func main() {
-
-	keyProvided := false
-	flag.Visit(func(f *flag.Flag) {
-		if f.Name == "k" {
-			keyProvided = true
-		}
-	})
+// Функция inputFiles возвращает входные файлы из аргументов или из файла --files0-from
+func inputFiles() ([]string, error) {
+	if files0From == "" {
+		return flag.Args(), nil
+	}
+	if flag.NArg() > 0 {
+		return nil, fmt.Errorf("extra operand '%s'\nfile operands cannot be combined with --files0-from", flag.Arg(0))
+	}
+	return readFiles0(files0From)
+}
 
-	file := flag.Arg(0)
+// Функция execute сортирует (или проверяет) входные файлы в соответствии с флагами
+func execute() error {
+	files, err := inputFiles()
+	if err != nil {
+		return err
+	}
 
-	var k int
-	var err error
-	if keyProvided {
-		k, err = strconv.Atoi(key)
-		if err != nil || k < 1 {
-			fmt.Fprintf(os.Stderr,
-				"invalid number at field start: invalid count at start of '%s'\n", key)
-			return
-		}
-		k--
+	opts, err := optionsFromFlags()
+	if err != nil {
+		return err
 	}
 
-	if k == 0 && !numeric {
-		lines, err := readStrings(file)
-		if err != nil {
-			fmt.Fprintln(os.Stderr, err.Error())
-			return
+	if check {
+		if top > 0 || bottom > 0 {
+			return errors.New("--top and --bottom are incompatible with -c")
 		}
-		var strSlice sort.StringSlice = lines
-		if reverse {
-			sort.Sort(sort.Reverse(strSlice))
-		} else {
-			sort.Strings(lines)
+		if len(files) > 1 {
+			return fmt.Errorf("extra operand '%s' not allowed with -c", files[1])
 		}
-		for _, line := range lines {
-			fmt.Println(line)
+		file := ""
+		if len(files) == 1 {
+			file = files[0]
 		}
-		return
+		return checkSorted(file, opts)
 	}
 
-	lines, err := readStringSlices(file, k)
-	if err != nil {
-		fmt.Fprintln(os.Stderr, err.Error())
-		return
+	// заголовок берется из начала входа, поэтому заголовки следующих файлов
+	// можно отбросить только при слиянии
+	if header && !merge && len(files) > 1 {
+		return errors.New("--header with several input files requires -m")
 	}
 
-	var data sort.Interface
-	if numeric {
-		data = numericSort{lines, k}
-	} else {
-		data = stringSort{lines, k}
+	if output == "" {
+		return sortFiles(files, os.Stdout, opts)
 	}
-	if reverse {
-		data = sort.Reverse(data)
+
+	// при слиянии чтение и запись чередуются, поэтому входной файл,
+	// совпадающий с выводом, предварительно копируется
+	if merge {
+		files = append([]string(nil), files...)
+		for i, file := range files {
+			if !isSameFile(file, output) {
+				continue
+			}
+			tmp, err := copyToTemp(file, tempDir)
+			if err != nil {
+				return err
+			}
+			defer os.Remove(tmp)
+			files[i] = tmp
+		}
+	}
+
+	out := &lazyFile{name: output}
+	err = sortFiles(files, out, opts)
+	if cerr := out.Close(); err == nil {
+		err = cerr
 	}
-	sort.Sort(data)
-	for _, line := range lines {
-		fmt.Println(strings.Join(line, " "))
+	return err
+}
+
+func main() {
+	if err := execute(); err != nil {
+		fmt.Fprintln(os.Stderr, "sort:", err.Error())
+		var d *sorter.DisorderError
+		if errors.As(err, &d) {
+			os.Exit(1)
+		}
+		os.Exit(2)
 	}
 }
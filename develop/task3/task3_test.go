@@ -1,98 +1,80 @@
 package main
 
 import (
+	"os"
 	"reflect"
 	"testing"
 )
 
-func TestStringToFloat(t *testing.T) {
-	f, ok := stringToFloat("3.14")
-	if f != 3.14 || !ok {
-		t.Logf(`stringToFloat("3.14") = %f, %v, expected 3.14, true`, f, ok)
-		t.Fail()
-	}
-
-	f, ok = stringToFloat("qwerty")
-	if f != 0 || ok {
-		t.Logf(`stringToFloat("qwerty") = %f, %v, expected 0, false`, f, ok)
-		t.Fail()
-	}
+func TestExpandArgs(t *testing.T) {
+	args := []string{"-nrk2,2n", "-k", "3", "-ut,", "-bk", "-1", "-S=10M", "-parallel", "2", "--", "-file"}
+	expected := []string{"-n", "-r", "-k", "2,2n", "-k", "3", "-u", "-t", ",", "-b", "-k", "-1", "-S=10M", "-parallel", "2", "--", "-file"}
 
-	f, ok = stringToFloat("nan")
-	if f != 0 || ok {
-		t.Logf(`stringToFloat("nan") = %f, %v, expected 0, false`, f, ok)
-		t.Fail()
+	res := expandArgs(args)
+	if !reflect.DeepEqual(res, expected) {
+		t.Errorf("expandArgs(%q) = %q, expected %q", args, res, expected)
 	}
 }
 
-func TestLessNum(t *testing.T) {
-	less := lessNum("0.1", "1.0")
-	if !less {
-		t.Logf(`stringToFloat("0.1", "1.0") = %v, expected true`, less)
-		t.Fail()
-	}
-
-	less = lessNum("0.1", "-1.0")
-	if less {
-		t.Logf(`stringToFloat("0.1", "-1.0") = %v, expected false`, less)
-		t.Fail()
-	}
-
-	less = lessNum("0.1", "0.1")
-	if less {
-		t.Logf(`stringToFloat("0.1", 0.1") = %v, expected false`, less)
-		t.Fail()
+func TestParseTab(t *testing.T) {
+	cases := []struct {
+		s, expected string
+	}{
+		{"", ""},
+		{",", ","},
+		{"\t", "\t"},
+		{"ж", "ж"},
+		{`\0`, "\x00"},
 	}
-
-	less = lessNum("qwe", "1.0")
-	if !less {
-		t.Logf(`stringToFloat("qwe", "1.0") = %v, expected true`, less)
-		t.Fail()
+	for _, c := range cases {
+		res, err := parseTab(c.s)
+		if err != nil || res != c.expected {
+			t.Errorf("parseTab(%q) = %q, %v, expected %q, nil", c.s, res, err, c.expected)
+		}
 	}
 
-	less = lessNum("qwe", "asd")
-	if less {
-		t.Logf(`stringToFloat("qwe", "asd") = %v, expected false`, less)
-		t.Fail()
+	if _, err := parseTab("::"); err == nil {
+		t.Error("parseTab(\"::\"): expected error")
 	}
 }
 
-func TestReadStringsUnique(t *testing.T) {
-	unique = true
-	file := "test.txt"
+func TestExecuteFormat(t *testing.T) {
+	defer setFlags(t, nil)
 
-	expected := []string{
-		"6 7 3",
-		"January 8 dog",
-		"11 8 0",
-		"March 8 -1",
-		"6 7 3 5 cat 12",
+	cases := []struct {
+		args            []string
+		input, expected string
+	}{
+		{
+			[]string{"--format=csv", "--header", "-k", "price:nr"},
+			"item,price\n\"nuts, salted\",3\nmilk,12\n\"bread\n(rye)\",5\n",
+			"item,price\nmilk,12\n\"bread\n(rye)\",5\n\"nuts, salted\",3\n",
+		},
+		{
+			[]string{"-format", "tsv", "-k2,2", "-u"},
+			"a\tx\nb\ty\nc\tx\n",
+			"a\tx\nb\ty\n",
+		},
+		{
+			[]string{"--format=jsonl", "-k", ".user.age:n", "-k", ".id"},
+			"{\"id\":2,\"user\":{\"age\":40}}\n{\"id\":1,\"user\":{\"age\":40}}\n{\"id\":3,\"user\":{\"age\":9}}\n",
+			"{\"id\":3,\"user\":{\"age\":9}}\n{\"id\":1,\"user\":{\"age\":40}}\n{\"id\":2,\"user\":{\"age\":40}}\n",
+		},
 	}
-
-	lines, err := readStrings(file)
-	if err != nil {
-		t.Fatalf("readStrings: %v", err)
-	}
-	if !reflect.DeepEqual(lines, expected) {
-		t.Fatal("result of readStrings is differ from expected")
+	for _, c := range cases {
+		files := writeFiles(t, c.input, "")
+		setFlags(t, append(c.args, "-o", files[1], files[0]))
+		if err := execute(); err != nil {
+			t.Fatalf("sort %q: %v", c.args, err)
+		}
+		if data, _ := os.ReadFile(files[1]); string(data) != c.expected {
+			t.Errorf("sort %q: output = %q, expected %q", c.args, data, c.expected)
+		}
 	}
-}
-
-func TestReadStringSlicesUniqueKey(t *testing.T) {
-	unique = true
-	k := 1
-	file := "test.txt"
 
-	expected := [][]string{
-		{"6", "7", "3"},
-		{"January", "8", "dog"},
-	}
-
-	lines, err := readStringSlices(file, k)
-	if err != nil {
-		t.Fatalf("readStringSlices: %v", err)
-	}
-	if !reflect.DeepEqual(lines, expected) {
-		t.Fatal("result of readStringSlices is differ from expected")
+	files := writeFiles(t, "n\n1\n", "n\n2\n")
+	setFlags(t, []string{"--header", files[0], files[1]})
+	if err := execute(); err == nil {
+		t.Error("sort --header with several files: expected error")
 	}
 }
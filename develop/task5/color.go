@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// colorMode - значение флага --color. Флаг без значения означает auto.
+type colorMode string
+
+func (c *colorMode) String() string { return string(*c) }
+
+func (c *colorMode) Set(s string) error {
+	switch s {
+	case "always", "yes", "force":
+		*c = "always"
+	case "never", "no", "none":
+		*c = "never"
+	case "auto", "tty", "if-tty", "true":
+		*c = "auto"
+	default:
+		return fmt.Errorf("invalid argument '%s' for --color", s)
+	}
+	return nil
+}
+
+func (c *colorMode) IsBoolFlag() bool { return true }
+
+// enabled сообщает, нужно ли выделять вывод цветом: с auto - только на терминале
+func (c colorMode) enabled() bool {
+	switch c {
+	case "always":
+		return true
+	case "auto":
+		info, err := os.Stdout.Stat()
+		return err == nil && info.Mode()&os.ModeCharDevice != 0 && os.Getenv("TERM") != "dumb"
+	}
+	return false
+}
+
+// colors - цвета SGR частей вывода, как в переменной GREP_COLORS GNU grep:
+// ms и mc - совпадения в найденных строках и строках контекста, sl и cx - сами строки,
+// fn - имя файла, ln - номер строки, bn - смещение, se - разделители.
+// rv меняет местами sl и cx с флагом -v, ne отключает очистку до конца строки.
+type colors struct {
+	ms, mc, sl, cx, fn, ln, bn, se string
+	rv, ne                         bool
+}
+
+// palette - цвета вывода, пустые значения не выделяют вывод цветом
+var palette colors
+
+// Функция parseColors возвращает цвета по умолчанию, измененные значением GREP_COLORS.
+// Неизвестные и некорректные параметры игнорируются.
+func parseColors(s string) colors {
+	c := colors{ms: "01;31", mc: "01;31", fn: "35", ln: "32", bn: "32", se: "36"}
+	caps := map[string]*string{
+		"ms": &c.ms, "mc": &c.mc, "sl": &c.sl, "cx": &c.cx,
+		"fn": &c.fn, "ln": &c.ln, "bn": &c.bn, "se": &c.se,
+	}
+	for _, item := range strings.Split(s, ":") {
+		name, val, hasVal := strings.Cut(item, "=")
+		switch {
+		case name == "rv" && !hasVal:
+			c.rv = true
+		case name == "ne" && !hasVal:
+			c.ne = true
+		case name == "mt" && hasVal:
+			c.ms, c.mc = val, val
+		case hasVal && caps[name] != nil:
+			*caps[name] = val
+		}
+	}
+	return c
+}
+
+// start возвращает последовательность, включающую цвет code
+func (c *colors) start(code string) string {
+	if c.ne {
+		return "\033[" + code + "m"
+	}
+	return "\033[" + code + "m\033[K"
+}
+
+// end возвращает последовательность, выключающую цвет
+func (c *colors) end() string {
+	if c.ne {
+		return "\033[m"
+	}
+	return "\033[m\033[K"
+}
+
+// wrap возвращает text, выделенный цветом code. Пустой code не выделяет текст.
+func (c *colors) wrap(code, text string) string {
+	if code == "" || text == "" {
+		return text
+	}
+	return c.start(code) + text + c.end()
+}
+
+// lineColors возвращает цвета строки и совпадений в ней для найденной строки
+// (selected) или строки контекста
+func (c *colors) lineColors(selected bool) (line, match string) {
+	sl, cx := c.sl, c.cx
+	if c.rv && invert {
+		sl, cx = cx, sl
+	}
+	if selected {
+		return sl, c.ms
+	}
+	return cx, c.mc
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestParseColors(t *testing.T) {
+	c := parseColors("mt=01;32:fn=:sl=1:rv:ne:xx=5:ln")
+	expected := colors{ms: "01;32", mc: "01;32", sl: "1", ln: "32", bn: "32", se: "36", rv: true, ne: true}
+	if c != expected {
+		t.Errorf("parseColors = %+v, expected %+v", c, expected)
+	}
+	if c := parseColors(""); c.ms != "01;31" || c.fn != "35" || c.ne {
+		t.Errorf("parseColors(\"\") = %+v, expected defaults", c)
+	}
+}
+
+func TestColorMode(t *testing.T) {
+	var c colorMode
+	for s, expected := range map[string]colorMode{"true": "auto", "always": "always", "no": "never", "if-tty": "auto"} {
+		if err := c.Set(s); err != nil || c != expected {
+			t.Errorf("Set(%q) = %q, %v, expected %q, nil", s, c, err, expected)
+		}
+	}
+	if err := c.Set("sometimes"); err == nil {
+		t.Error(`Set("sometimes"): expected error`)
+	}
+	if colorMode("never").enabled() || !colorMode("always").enabled() {
+		t.Error("enabled: never and always are not respected")
+	}
+}
+
+func TestSearchColor(t *testing.T) {
+	palette = parseColors("")
+	numerate = true
+	defer func() { palette, numerate = colors{}, false }()
+
+	testSearch(t, "xay\nb\n", "\033[32m\033[K1\033[m\033[K\033[36m\033[K:\033[m\033[K"+
+		"x\033[01;31m\033[Ka\033[m\033[Ky\n")
+}
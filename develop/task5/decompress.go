@@ -0,0 +1,165 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"io"
+	"os"
+
+	"github.com/klauspost/compress/zstd"
+
+	"go-grep/grep"
+)
+
+// Сигнатуры сжатых файлов и архивов
+var (
+	gzipMagic  = []byte{0x1f, 0x8b}
+	bzip2Magic = []byte("BZh")
+	zstdMagic  = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	zipMagic   = []byte("PK\x03\x04")
+	tarMagic   = []byte("ustar")
+)
+
+// tarMagicOffset - смещение сигнатуры в заголовке tar
+const tarMagicOffset = 257
+
+// Функция peekHead возвращает до n первых байт br, не ожидая данных сверх
+// прочитанных первым чтением, чтобы поиск в коротком потоке вроде Stdin не блокировался
+func peekHead(br *bufio.Reader, n int) []byte {
+	br.Peek(1)
+	if b := br.Buffered(); b < n {
+		n = b
+	}
+	head, _ := br.Peek(n)
+	return head
+}
+
+// Функция decompressReader возвращает распакованный поток br, определяя формат
+// по сигнатуре, и функцию, освобождающую ресурсы распаковки.
+// Несжатые данные возвращаются без изменений.
+func decompressReader(br *bufio.Reader) (io.Reader, func(), error) {
+	head := peekHead(br, len(zstdMagic))
+	switch {
+	case bytes.HasPrefix(head, gzipMagic):
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, nil, err
+		}
+		return gz, func() { gz.Close() }, nil
+	case bytes.HasPrefix(head, bzip2Magic):
+		return bzip2.NewReader(br), func() {}, nil
+	case bytes.HasPrefix(head, zstdMagic):
+		d, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, nil, err
+		}
+		return d, d.Close, nil
+	}
+	return br, func() {}, nil
+}
+
+// Функция grepDecompressed ищет строки в распакованном файле (-z), а если это
+// архив tar или zip - в каждом его файле, выводя его имя как архив!файл
+func grepDecompressed(w *bufio.Writer, f io.Reader, name string, prefix bool, m grep.Matcher) (int, error) {
+	br := bufio.NewReader(f)
+	r, release, err := decompressReader(br)
+	if err != nil {
+		return 0, &os.PathError{Op: "decompress", Path: name, Err: err}
+	}
+	defer release()
+
+	// сигнатура tar находится далеко от начала, поэтому в несжатом потоке
+	// она ищется только среди уже прочитанных данных
+	compressed := r != io.Reader(br)
+	var head []byte
+	if compressed {
+		br = bufio.NewReader(r)
+		head, _ = br.Peek(tarMagicOffset + len(tarMagic))
+	} else {
+		head = peekHead(br, tarMagicOffset+len(tarMagic))
+	}
+	switch {
+	case len(head) == tarMagicOffset+len(tarMagic) && bytes.Equal(head[tarMagicOffset:], tarMagic):
+		return grepTar(w, br, name, m)
+	case bytes.HasPrefix(head, zipMagic):
+		// zip читается с произвольных позиций: несжатый файл открывается как есть,
+		// а распакованные данные и Stdin читаются в память
+		if file, ok := f.(*os.File); ok && !compressed {
+			info, err := file.Stat()
+			if err != nil {
+				return 0, err
+			}
+			return grepZip(w, file, info.Size(), name, m)
+		}
+		data, err := io.ReadAll(br)
+		if err != nil {
+			return 0, err
+		}
+		return grepZip(w, bytes.NewReader(data), int64(len(data)), name, m)
+	}
+	return grepReader(w, br, name, prefix, m)
+}
+
+// Функция grepEntry ищет строки в файле архива, распаковывая его, если он сжат.
+// Вложенные архивы не просматриваются.
+func grepEntry(w *bufio.Writer, r io.Reader, name string, m grep.Matcher) (int, error) {
+	dr, release, err := decompressReader(bufio.NewReader(r))
+	if err != nil {
+		return 0, &os.PathError{Op: "decompress", Path: name, Err: err}
+	}
+	defer release()
+	// в архиве несколько файлов, поэтому строки предваряются именами, если нет -h
+	return grepReader(w, dr, name, !noName, m)
+}
+
+// Функция grepTar ищет строки в обычных файлах архива tar
+func grepTar(w *bufio.Writer, r io.Reader, name string, m grep.Matcher) (int, error) {
+	tr := tar.NewReader(r)
+	total := 0
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return total, nil
+		}
+		if err != nil {
+			return total, &os.PathError{Op: "read", Path: name, Err: err}
+		}
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+		n, err := grepEntry(w, tr, name+"!"+h.Name, m)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+}
+
+// Функция grepZip ищет строки в файлах архива zip
+func grepZip(w *bufio.Writer, r io.ReaderAt, size int64, name string, m grep.Matcher) (int, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return 0, &os.PathError{Op: "read", Path: name, Err: err}
+	}
+	total := 0
+	for _, zf := range zr.File {
+		if zf.FileInfo().IsDir() {
+			continue
+		}
+		fr, err := zf.Open()
+		if err != nil {
+			return total, &os.PathError{Op: "open", Path: name + "!" + zf.Name, Err: err}
+		}
+		n, err := grepEntry(w, fr, name+"!"+zf.Name, m)
+		fr.Close()
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
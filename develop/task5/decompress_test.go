@@ -0,0 +1,160 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// grepDecompressedFile ищет патерн ERROR в файле с флагом -z и возвращает вывод
+func grepDecompressedFile(t *testing.T, file string) string {
+	t.Helper()
+	decompress = true
+	defer func() { decompress = false }()
+
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	m := mustCompile(t, "ERROR")
+	if _, err := grepFile(w, file, true, m); err != nil {
+		t.Fatalf("grepFile(%q): %v", file, err)
+	}
+	return buf.String()
+}
+
+func gzipData(t *testing.T, data string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte(data))
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecompress(t *testing.T) {
+	dir := t.TempDir()
+	input := "ERROR compressed line\nok\n"
+
+	var zbuf bytes.Buffer
+	zw, _ := zstd.NewWriter(&zbuf)
+	zw.Write([]byte(input))
+	zw.Close()
+
+	files := map[string][]byte{
+		"log.gz":  gzipData(t, input),
+		"log.zst": zbuf.Bytes(),
+		"log.txt": []byte(input),
+	}
+	for name, data := range files {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if res, expected := grepDecompressedFile(t, p), p+":ERROR compressed line\n"; res != expected {
+			t.Errorf("grep -z %s = %q, expected %q", name, res, expected)
+		}
+	}
+
+	// bzip2 нельзя сжать стандартной библиотекой, поэтому файл получен командой bzip2
+	p := filepath.Join("testdata", "input.txt.bz2")
+	if res, expected := grepDecompressedFile(t, p), p+":ERROR compressed line\n"; res != expected {
+		t.Errorf("grep -z input.txt.bz2 = %q, expected %q", res, expected)
+	}
+}
+
+func TestDecompressStream(t *testing.T) {
+	// короткая строка потока выводится, не дожидаясь следующих данных
+	in, inw := io.Pipe()
+	outr, out := io.Pipe()
+	m := mustCompile(t, "ERROR")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		w := bufio.NewWriter(out)
+		grepDecompressed(w, in, "(standard input)", false, m)
+		w.Flush()
+		out.Close()
+	}()
+	inw.Write([]byte("ERROR short\n"))
+
+	line := make(chan string, 1)
+	go func() {
+		s, _ := bufio.NewReader(outr).ReadString('\n')
+		line <- s
+	}()
+	select {
+	case s := <-line:
+		if s != "ERROR short\n" {
+			t.Errorf("grep -z on a stream = %q, expected \"ERROR short\\n\"", s)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("grep -z blocks on a short stream")
+	}
+	inw.Close()
+	<-done
+}
+
+func TestDecompressArchives(t *testing.T) {
+	dir := t.TempDir()
+	entries := []struct {
+		name string
+		data []byte
+	}{
+		{"a.txt", []byte("ERROR in a\nok\n")},
+		{"sub/b.log.gz", gzipData(t, "ok\nERROR in b\n")},
+		{"c.txt", []byte("ok\n")},
+	}
+
+	var tbuf bytes.Buffer
+	tw := tar.NewWriter(&tbuf)
+	tw.WriteHeader(&tar.Header{Name: "sub/", Typeflag: tar.TypeDir, Mode: 0o755})
+	for _, e := range entries {
+		tw.WriteHeader(&tar.Header{Name: e.name, Mode: 0o644, Size: int64(len(e.data))})
+		tw.Write(e.data)
+	}
+	tw.Close()
+
+	var zbuf bytes.Buffer
+	zw := zip.NewWriter(&zbuf)
+	for _, e := range entries {
+		f, _ := zw.Create(e.name)
+		f.Write(e.data)
+	}
+	zw.Close()
+
+	files := map[string][]byte{
+		"logs.tar":    tbuf.Bytes(),
+		"logs.tar.gz": gzipData(t, tbuf.String()),
+		"logs.zip":    zbuf.Bytes(),
+		"logs.zip.gz": gzipData(t, zbuf.String()),
+	}
+	for name, data := range files {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		expected := p + "!a.txt:ERROR in a\n" + p + "!sub/b.log.gz:ERROR in b\n"
+		if res := grepDecompressedFile(t, p); res != expected {
+			t.Errorf("grep -z %s = %q, expected %q", name, res, expected)
+		}
+	}
+
+	// поврежденный архив - ошибка
+	p := filepath.Join(dir, "bad.gz")
+	os.WriteFile(p, []byte{0x1f, 0x8b, 0, 0}, 0o644)
+	decompress = true
+	defer func() { decompress = false }()
+	if _, err := grepFile(bufio.NewWriter(&bytes.Buffer{}), p, false, mustCompile(t, "x")); err == nil {
+		t.Error("grepFile with a corrupted gzip file: expected error")
+	}
+}
@@ -1,3 +1,5 @@
 module go-grep
 
 go 1.21.4
+
+require github.com/klauspost/compress v1.17.11
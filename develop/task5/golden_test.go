@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Эталонные файлы testdata/*.golden получены командой grep ARGS (GNU grep 3.8)
+// в каталоге task5 без переменной GREP_COLORS
+var goldenCases = []struct {
+	name string
+	args []string
+}{
+	{"context_after", []string{"-n", "-A", "2", "ERROR", "testdata/input.txt"}},
+	{"context_before_after", []string{"-B", "1", "-A", "1", "ERROR", "testdata/input.txt"}},
+	{"context_zero", []string{"-C", "0", "WARN", "testdata/input.txt"}},
+	{"context_overlap", []string{"-n", "-C", "3", "WARN", "testdata/input.txt"}},
+	{"group_separator", []string{"-A", "1", "--group-separator=::", "WARN", "testdata/input.txt"}},
+	{"no_group_separator", []string{"-A", "1", "--no-group-separator", "WARN", "testdata/input.txt"}},
+	{"max_count_context", []string{"-n", "-m", "2", "-A", "3", "ERROR", "testdata/input.txt"}},
+	{"max_count_trailing_match", []string{"-n", "-m", "1", "-A", "5", "ERROR", "testdata/input.txt"}},
+	{"max_count_before", []string{"-m", "2", "-B", "2", "INFO", "testdata/input.txt"}},
+	{"count_max", []string{"-c", "-m", "2", "ERROR", "testdata/input.txt"}},
+	{"count_invert", []string{"-c", "-v", "INFO", "testdata/input.txt"}},
+	{"count_context", []string{"-c", "-A", "2", "ERROR", "testdata/input.txt"}},
+	{"invert_context", []string{"-v", "-n", "-A", "1", "-e", "INFO", "-e", "DEBUG", "testdata/input.txt"}},
+	{"invert_max", []string{"-v", "-m", "3", "-B", "1", "INFO", "testdata/input.txt"}},
+	{"only_matching_context", []string{"-o", "-n", "-A", "1", "ERROR", "testdata/input.txt"}},
+	{"only_matching_longest", []string{"-o", "-n", "-e", "start", "-e", "started", "-e", "clean", "-e", "cleanup", "testdata/input.txt"}},
+	{"only_matching_longest_ere", []string{"-o", "-E", "ERR|ERROR [a-z]+", "testdata/input.txt"}},
+	{"color_longest", []string{"--color=always", "-e", "clean", "-e", "cleanup", "-e", "start", "-e", "started", "testdata/input.txt"}},
+	{"byte_offset_context", []string{"-b", "-B", "1", "WARN", "testdata/input.txt"}},
+	{"multi_files", []string{"-A", "1", "ERROR", "testdata/input.txt", "testdata/input2.txt"}},
+	{"multi_files_no_context", []string{"-n", "ERROR", "testdata/input.txt", "testdata/input2.txt"}},
+	{"multi_files_count", []string{"-c", "ERROR", "testdata/input.txt", "testdata/input2.txt"}},
+}
+
+// setFlags сбрасывает флаги к значениям по умолчанию и разбирает args
+func setFlags(t *testing.T, args []string) {
+	t.Helper()
+	patterns, patternFiles, includes, excludes, excludeDirs = nil, nil, nil, nil, nil
+	color = ""
+	flag.VisitAll(func(f *flag.Flag) {
+		switch f.Value.(type) {
+		case *stringList, *colorMode:
+		default:
+			if !strings.HasPrefix(f.Name, "test.") {
+				f.Value.Set(f.DefValue)
+			}
+		}
+	})
+	if err := flag.CommandLine.Parse(args); err != nil {
+		t.Fatalf("flag.Parse(%q): %v", args, err)
+	}
+}
+
+func TestGolden(t *testing.T) {
+	defer func() {
+		setFlags(t, nil)
+		palette = colors{}
+	}()
+	for _, c := range goldenCases {
+		setFlags(t, c.args)
+		palette = colors{}
+		if color.enabled() {
+			palette = parseColors("")
+		}
+		expected, err := os.ReadFile(filepath.Join("testdata", c.name+".golden"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var buf bytes.Buffer
+		w := bufio.NewWriter(&buf)
+		if _, err := execute(flag.Args(), w); err != nil {
+			t.Fatalf("%s: execute: %v", c.name, err)
+		}
+		w.Flush()
+		if !bytes.Equal(buf.Bytes(), expected) {
+			t.Errorf("%s: grep %q:\n%s\nexpected:\n%s", c.name, c.args, buf.Bytes(), expected)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package grep
+
+import (
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ahoCorasick ищет любую из множества фиксированных строк за один проход по строке.
+// Автомат хранится как полная таблица переходов по классам байтов: байты,
+// не встречающиеся в патернах, относятся к одному классу, поэтому таблица
+// занимает узлы * классы, а не узлы * 256 элементов.
+// Совпадения выбираются как в GNU grep: самое левое, а из них самое длинное.
+type ahoCorasick struct {
+	// class - класс каждого байта с учетом регистра при fold, classes - число классов
+	class   [256]uint16
+	classes int
+	// delta - переходы автомата, depth - длина префикса патерна в узле
+	delta []int32
+	depth []int32
+	// out - ближайший по суффиксным ссылкам узел, где заканчивается патерн, или -1
+	out []int32
+	n   int
+}
+
+// Функция newAhoCorasick строит автомат для непустых патернов ps.
+// С fold регистр букв ASCII не учитывается.
+func newAhoCorasick(ps []string, fold bool) *ahoCorasick {
+	a := &ahoCorasick{n: len(ps)}
+	for _, p := range ps {
+		for i := 0; i < len(p); i++ {
+			c := p[i]
+			if fold {
+				c = lowerASCII(c)
+			}
+			if a.class[c] == 0 {
+				a.classes++
+				a.class[c] = uint16(a.classes)
+			}
+		}
+	}
+	a.classes++
+	if fold {
+		for c := 'A'; c <= 'Z'; c++ {
+			a.class[c] = a.class[c+'a'-'A']
+		}
+	}
+
+	// бор патернов, 0 в таблице переходов - нет перехода
+	var trie []int32
+	var terminal []bool
+	newNode := func(depth int32) int32 {
+		trie = append(trie, make([]int32, a.classes)...)
+		terminal = append(terminal, false)
+		a.depth = append(a.depth, depth)
+		return int32(len(terminal) - 1)
+	}
+	newNode(0)
+	for _, p := range ps {
+		node := int32(0)
+		for i := 0; i < len(p); i++ {
+			k := node*int32(a.classes) + int32(a.class[p[i]])
+			if trie[k] == 0 {
+				next := newNode(a.depth[node] + 1)
+				trie[k] = next
+			}
+			node = trie[k]
+		}
+		terminal[node] = true
+	}
+
+	// обход в ширину: суффиксные ссылки и полная таблица переходов
+	nodes := len(terminal)
+	a.delta = make([]int32, nodes*a.classes)
+	a.out = make([]int32, nodes)
+	fail := make([]int32, nodes)
+	a.out[0] = -1
+	queue := []int32{0}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		row := node * int32(a.classes)
+		for c := int32(0); c < int32(a.classes); c++ {
+			next := trie[row+c]
+			if next == 0 {
+				if node != 0 {
+					a.delta[row+c] = a.delta[fail[node]*int32(a.classes)+c]
+				}
+				continue
+			}
+			a.delta[row+c] = next
+			if node != 0 {
+				fail[next] = a.delta[fail[node]*int32(a.classes)+c]
+			}
+			a.out[next] = a.out[fail[next]]
+			if terminal[next] {
+				a.out[next] = next
+			}
+			queue = append(queue, next)
+		}
+	}
+	return a
+}
+
+func lowerASCII(c byte) byte {
+	if 'A' <= c && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
+
+// Функция foldableASCII сообщает, что регистр букв в строке можно не учитывать
+// побайтно: в строке нет символов за пределами ASCII, имеющих другой регистр.
+// Символы вроде знака Кельвина, совпадающие без учета регистра с буквами ASCII,
+// при этом не находятся.
+func foldableASCII(s string) bool {
+	for _, r := range s {
+		if r >= utf8.RuneSelf && unicode.SimpleFold(r) != r {
+			return false
+		}
+	}
+	return true
+}
+
+// find возвращает границы самого левого и самого длинного совпадения в b
+// после позиции from или -1, -1
+func (a *ahoCorasick) find(b []byte, from int) (int, int) {
+	start, end := -1, -1
+	node := int32(0)
+	for i := from; i < len(b); i++ {
+		node = a.delta[node*int32(a.classes)+int32(a.class[b[i]])]
+		// из патернов, заканчивающихся в i, самый длинный начинается раньше всех
+		if o := a.out[node]; o >= 0 {
+			if s := i + 1 - int(a.depth[o]); start < 0 || s <= start {
+				start, end = s, i+1
+			}
+		}
+		// последующие совпадения начинаются не раньше префикса патерна в узле
+		if start >= 0 && i+1-int(a.depth[node]) > start {
+			break
+		}
+	}
+	return start, end
+}
+
+// Match сообщает, есть ли в b совпадение
+func (a *ahoCorasick) Match(b []byte) bool {
+	node := int32(0)
+	for i := 0; i < len(b); i++ {
+		node = a.delta[node*int32(a.classes)+int32(a.class[b[i]])]
+		if a.out[node] >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// FindIndex возвращает границы первого совпадения в b или nil
+func (a *ahoCorasick) FindIndex(b []byte) []int {
+	s, e := a.find(b, 0)
+	if s < 0 {
+		return nil
+	}
+	return []int{s, e}
+}
+
+// FindAllIndex возвращает границы не более n (все при n < 0) непересекающихся совпадений в b
+func (a *ahoCorasick) FindAllIndex(b []byte, n int) [][]int {
+	var res [][]int
+	for pos := 0; n < 0 || len(res) < n; {
+		s, e := a.find(b, pos)
+		if s < 0 {
+			break
+		}
+		res = append(res, []int{s, e})
+		pos = e
+	}
+	return res
+}
+
+func (a *ahoCorasick) String() string {
+	return "aho-corasick(" + strconv.Itoa(a.n) + " patterns)"
+}
+
+// lineSet находит строку целиком, если она равна одному из патернов (-F -x).
+// С fold регистр букв ASCII не учитывается.
+type lineSet struct {
+	set  map[string]struct{}
+	fold bool
+}
+
+func newLineSet(ps []string, fold bool) *lineSet {
+	l := &lineSet{set: make(map[string]struct{}, len(ps)), fold: fold}
+	for _, p := range ps {
+		l.set[l.key(p)] = struct{}{}
+	}
+	return l
+}
+
+func (l *lineSet) key(s string) string {
+	if !l.fold {
+		return s
+	}
+	b := []byte(s)
+	for i := range b {
+		b[i] = lowerASCII(b[i])
+	}
+	return string(b)
+}
+
+// Match сообщает, что b равна одному из патернов
+func (l *lineSet) Match(b []byte) bool {
+	if !l.fold {
+		_, ok := l.set[string(b)]
+		return ok
+	}
+	_, ok := l.set[l.key(string(b))]
+	return ok
+}
+
+// FindIndex возвращает границы всей b, если она равна одному из патернов, или nil
+func (l *lineSet) FindIndex(b []byte) []int {
+	if !l.Match(b) {
+		return nil
+	}
+	return []int{0, len(b)}
+}
+
+func (l *lineSet) FindAllIndex(b []byte, n int) [][]int {
+	if n == 0 || !l.Match(b) {
+		return nil
+	}
+	return [][]int{{0, len(b)}}
+}
+
+func (l *lineSet) String() string {
+	return "line-set(" + strconv.Itoa(len(l.set)) + " patterns)"
+}
@@ -0,0 +1,149 @@
+package grep
+
+import (
+	"math/rand"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// randomWord возвращает случайную строку длины от 1 до n из букв alphabet
+func randomWord(r *rand.Rand, alphabet string, n int) string {
+	b := make([]byte, 1+r.Intn(n))
+	for i := range b {
+		b[i] = alphabet[r.Intn(len(alphabet))]
+	}
+	return string(b)
+}
+
+// alternation возвращает регулярное выражение, находящее самое левое
+// и самое длинное совпадение с одной из строк ps
+func alternation(ps []string, fold bool) *regexp.Regexp {
+	quoted := make([]string, len(ps))
+	for i, p := range ps {
+		quoted[i] = regexp.QuoteMeta(p)
+	}
+	expr := strings.Join(quoted, "|")
+	if fold {
+		expr = "(?i)" + expr
+	}
+	re := regexp.MustCompile(expr)
+	re.Longest()
+	return re
+}
+
+func TestAhoCorasickRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 300; i++ {
+		fold := i%2 == 1
+		ps := make([]string, 1+r.Intn(8))
+		for j := range ps {
+			ps[j] = randomWord(r, "abcAB", 4)
+		}
+		text := []byte(randomWord(r, "abcAB ", 40))
+
+		a := newAhoCorasick(ps, fold)
+		re := alternation(ps, fold)
+		if res, expected := a.FindAllIndex(text, -1), re.FindAllIndex(text, -1); !reflect.DeepEqual(res, expected) {
+			t.Fatalf("patterns %q fold %v in %q: FindAllIndex = %v, expected %v", ps, fold, text, res, expected)
+		}
+		if a.Match(text) != re.Match(text) {
+			t.Fatalf("patterns %q fold %v in %q: Match = %v", ps, fold, text, a.Match(text))
+		}
+	}
+}
+
+func TestAhoCorasick(t *testing.T) {
+	a := newAhoCorasick([]string{"he", "she", "hers", "his"}, false)
+	if res := a.FindAllIndex([]byte("ushers ahis"), -1); !reflect.DeepEqual(res, [][]int{{1, 4}, {8, 11}}) {
+		t.Errorf("FindAllIndex = %v, expected [[1 4] [8 11]]", res)
+	}
+	if res := a.FindIndex([]byte("hershe")); !reflect.DeepEqual(res, []int{0, 4}) {
+		t.Errorf("FindIndex = %v, expected [0 4]", res)
+	}
+	if a.FindIndex([]byte("HERS")) != nil || a.Match([]byte("h e")) {
+		t.Error("unexpected match")
+	}
+	if a := newAhoCorasick([]string{"Go", "ß"}, true); !a.Match([]byte("gO")) || !a.Match([]byte("ß")) {
+		t.Error("folded Match = false, expected true")
+	}
+}
+
+func TestLineSet(t *testing.T) {
+	l := newLineSet([]string{"foo", "Bar"}, true)
+	if !l.Match([]byte("FOO")) || !l.Match([]byte("bar")) || l.Match([]byte("foo ")) {
+		t.Error("lineSet.Match with fold is wrong")
+	}
+	if res := l.FindAllIndex([]byte("bAr"), -1); !reflect.DeepEqual(res, [][]int{{0, 3}}) {
+		t.Errorf("FindAllIndex = %v, expected [[0 3]]", res)
+	}
+}
+
+func TestFixedMatcher(t *testing.T) {
+	cases := []struct {
+		ps       []string
+		expected string
+	}{
+		{[]string{"a"}, `\Qa\E`},
+		{[]string{"a", "b"}, "aho-corasick(2 patterns)"},
+		{[]string{"a", ""}, `(?:\Qa\E)|(?:\Q\E)`},
+	}
+	for _, c := range cases {
+		m := mustCompile(t, c.ps, MatchOptions{Syntax: Fixed})
+		if m.re.String() != c.expected {
+			t.Errorf("Compile(%q) = %s, expected %s", c.ps, m.re.String(), c.expected)
+		}
+	}
+
+	// буквы за пределами ASCII без учета регистра находит регулярное выражение
+	o := MatchOptions{Syntax: Fixed, IgnoreCase: true}
+	m := mustCompile(t, []string{"ab", "Ωx"}, o)
+	if !m.Match([]byte("AB")) || !m.Match([]byte("ωX")) {
+		t.Error("-i -F: expected matches")
+	}
+	if _, ok := m.re.(*regexp.Regexp); !ok {
+		t.Errorf("-i -F with non-ASCII letters uses %s, expected regexp", m.re.String())
+	}
+
+	o.Word = true
+	testMatch(t, []string{"foo", "foobar"}, o, []string{"FOOBAR baz", "foobarx", "xfoo foo"}, []bool{true, false, true})
+
+	o.Word, o.Line = false, true
+	testMatch(t, []string{"foo", "bar"}, o, []string{"FOO", "foo bar", "Bar"}, []bool{true, false, true})
+}
+
+// benchmarkFixed ищет n случайных фиксированных строк в тексте с помощью newFinder
+func benchmarkFixed(b *testing.B, n int, newFinder func([]string) finder) {
+	r := rand.New(rand.NewSource(1))
+	ps := make([]string, n)
+	for i := range ps {
+		ps[i] = randomWord(r, "abcdefghijklmnopqrstuvwxyz", 12) + "xq"
+	}
+	lines := make([][]byte, 1000)
+	for i := range lines {
+		lines[i] = []byte(randomWord(r, "abcdefghijklmnopqrstuvwxyz ", 100))
+	}
+	f := newFinder(ps)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, line := range lines {
+			f.Match(line)
+		}
+	}
+}
+
+func newRegexpFinder(ps []string) finder {
+	quoted := make([]string, len(ps))
+	for i, p := range ps {
+		quoted[i] = `\Q` + p + `\E`
+	}
+	return regexp.MustCompile("(?:" + strings.Join(quoted, ")|(?:") + ")")
+}
+
+func newAhoCorasickFinder(ps []string) finder { return newAhoCorasick(ps, false) }
+
+func BenchmarkFixed100Regexp(b *testing.B)       { benchmarkFixed(b, 100, newRegexpFinder) }
+func BenchmarkFixed100AhoCorasick(b *testing.B)  { benchmarkFixed(b, 100, newAhoCorasickFinder) }
+func BenchmarkFixed1000Regexp(b *testing.B)      { benchmarkFixed(b, 1000, newRegexpFinder) }
+func BenchmarkFixed1000AhoCorasick(b *testing.B) { benchmarkFixed(b, 1000, newAhoCorasickFinder) }
@@ -0,0 +1,331 @@
+package grep
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Syntax - синтаксис патернов
+type Syntax int
+
+const (
+	// Basic - базовые регулярные выражения POSIX (-G)
+	Basic Syntax = iota
+	// Extended - расширенные регулярные выражения POSIX (-E)
+	Extended
+	// Perl - регулярные выражения RE2, похожие на выражения Perl (-P)
+	Perl
+	// Fixed - фиксированные строки (-F)
+	Fixed
+)
+
+// MatchOptions описывает, как патерны сопоставляются со строками.
+// Нулевое значение соответствует grep без флагов: базовые регулярные выражения
+// с учетом регистра, совпадающие с любой частью строки.
+type MatchOptions struct {
+	Syntax Syntax
+	// IgnoreCase не учитывает регистр букв (-i)
+	IgnoreCase bool
+	// Word находит только совпадения, являющиеся целыми словами (-w)
+	Word bool
+	// Line находит только совпадения со всей строкой (-x), Word при этом не учитывается
+	Line bool
+}
+
+// Matcher находит в строках совпадения с патернами.
+// Matcher, возвращаемый Compile, можно использовать из нескольких горутин.
+type Matcher interface {
+	// Match сообщает, соответствует ли строка патернам
+	Match(line []byte) bool
+	// FindAll возвращает границы всех непересекающихся совпадений в строке
+	FindAll(line []byte) [][]int
+}
+
+// SubmatchMatcher - Matcher, находящий также группы захвата регулярных выражений
+type SubmatchMatcher interface {
+	Matcher
+	// FindAllSubmatch возвращает границы всех совпадений в строке и их групп захвата
+	// в формате regexp.FindAllSubmatchIndex
+	FindAllSubmatch(line []byte) [][]int
+}
+
+// finder ищет совпадения с патернами. Его реализуют *regexp.Regexp, wordRegexp, ahoCorasick и lineSet.
+type finder interface {
+	Match(b []byte) bool
+	FindIndex(b []byte) []int
+	FindAllIndex(b []byte, n int) [][]int
+	String() string
+}
+
+// matcher реализует SubmatchMatcher для патернов, переданных Compile
+type matcher struct {
+	// re - объединение патернов, nil - патернов нет и ни одна строка не соответствует
+	re finder
+	// full - объединение патернов, совпадающее только со всей строкой, для поиска слов (-w)
+	full finder
+	// next и fullNext - варианты re и full, начинающиеся с любого символа, для поиска
+	// с середины строки: символ перед позицией поиска передается им, чтобы ^ не совпадал,
+	// а \b учитывал этот символ. nil - поиск с середины строки не зависит от предыдущего символа.
+	next     finder
+	fullNext finder
+	word     bool
+}
+
+// Compile переводит патерны в синтаксис RE2 и объединяет их в одно регулярное выражение
+// с учетом опций o. Множество фиксированных строк ищется без регулярных выражений.
+// Без патернов ни одна строка не соответствует Matcher.
+func Compile(patterns []string, o MatchOptions) (Matcher, error) {
+	if len(patterns) == 0 {
+		return &matcher{}, nil
+	}
+	if m := newFixedMatcher(patterns, o); m != nil {
+		return m, nil
+	}
+
+	res := make([]string, len(patterns))
+	for i, p := range patterns {
+		var err error
+		switch o.Syntax {
+		case Fixed:
+			res[i] = `\Q` + p + `\E`
+			if strings.Contains(p, `\E`) {
+				res[i] = regexp.QuoteMeta(p)
+			}
+		case Extended:
+			res[i], err = translateERE(p)
+		case Perl:
+			res[i] = p
+		default:
+			res[i], err = translateBRE(p)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	expr := res[0]
+	if len(res) > 1 {
+		expr = "(?:" + strings.Join(res, ")|(?:") + ")"
+	}
+	full := "^(?:" + expr + ")$"
+	if o.Line {
+		expr = full
+	}
+	if o.IgnoreCase {
+		expr, full = "(?i)"+expr, "(?i)"+full
+	}
+
+	// как в POSIX, из совпадений с одного места выбирается самое длинное,
+	// а в синтаксисе Perl - первое по порядку альтернатив; там же \b, как в RE2,
+	// учитывает только символы ASCII
+	posix := o.Syntax != Perl
+	re, err := compileRegexp(expr, posix, posix)
+	if err != nil {
+		return nil, err
+	}
+	mustCompile := func(expr string) submatchFinder {
+		re, err := compileRegexp(expr, posix, posix)
+		if err != nil {
+			panic(err)
+		}
+		return re
+	}
+	m := &matcher{re: re, word: o.Word && !o.Line}
+	if m.word {
+		m.full = mustCompile(full)
+		// выражения для поиска с середины строки начинаются с предыдущего символа
+		m.next = mustCompile("(?s:.)(?:" + expr + ")")
+		m.fullNext = mustCompile("^(?s:.)(?:" + expr + ")$")
+	}
+	return m, nil
+}
+
+// Функция newFixedMatcher возвращает matcher для множества фиксированных строк,
+// не использующий регулярные выражения, или nil, если он неприменим.
+// Единственную строку regexp находит не медленнее, а пустая строка
+// и IgnoreCase для букв за пределами ASCII обрабатываются регулярным выражением.
+func newFixedMatcher(ps []string, o MatchOptions) *matcher {
+	if o.Syntax != Fixed || len(ps) < 2 {
+		return nil
+	}
+	for _, p := range ps {
+		if p == "" || o.IgnoreCase && !foldableASCII(p) {
+			return nil
+		}
+	}
+	if o.Line {
+		return &matcher{re: newLineSet(ps, o.IgnoreCase)}
+	}
+	m := &matcher{re: newAhoCorasick(ps, o.IgnoreCase), word: o.Word}
+	if o.Word {
+		m.full = newLineSet(ps, o.IgnoreCase)
+	}
+	return m
+}
+
+// Match сообщает, соответствует ли строка патернам
+func (m *matcher) Match(line []byte) bool {
+	if m.re == nil {
+		return false
+	}
+	if m.word {
+		return m.findWord(line, 0) != nil
+	}
+	return m.re.Match(line)
+}
+
+// FindAllSubmatch возвращает границы всех совпадений в строке и их групп захвата
+// в формате regexp.FindAllSubmatchIndex
+func (m *matcher) FindAllSubmatch(line []byte) [][]int {
+	if m.re == nil {
+		return nil
+	}
+	re, ok := m.re.(submatchFinder)
+	if !ok {
+		// у фиксированных строк нет групп захвата
+		return m.FindAll(line)
+	}
+	if !m.word {
+		return re.FindAllSubmatchIndex(line, -1)
+	}
+	// группы совпадения-слова находятся сопоставлением с ним всего выражения
+	var res [][]int
+	for _, loc := range m.FindAll(line) {
+		full, from := m.fullAt(line, loc[0])
+		sub := full.(submatchFinder).FindSubmatchIndex(line[from:loc[1]])
+		for i := range sub {
+			if sub[i] >= 0 {
+				sub[i] += from
+			}
+		}
+		sub[0] = loc[0]
+		res = append(res, sub)
+	}
+	return res
+}
+
+// FindAll возвращает границы всех совпадений в строке
+func (m *matcher) FindAll(line []byte) [][]int {
+	if m.re == nil {
+		return nil
+	}
+	if !m.word {
+		return m.re.FindAllIndex(line, -1)
+	}
+	var res [][]int
+	for pos := 0; pos <= len(line); {
+		loc := m.findWord(line, pos)
+		if loc == nil {
+			break
+		}
+		res = append(res, loc)
+		pos = loc[1]
+		if loc[0] == loc[1] {
+			if pos == len(line) {
+				break
+			}
+			_, size := utf8.DecodeRune(line[pos:])
+			pos += size
+		}
+	}
+	return res
+}
+
+// String возвращает выражение, которым ищутся совпадения
+func (m *matcher) String() string {
+	if m.re == nil {
+		return ""
+	}
+	return m.re.String()
+}
+
+// findWord возвращает границы первого совпадения после позиции from, являющегося целым словом:
+// до и после него нет букв, цифр или '_'. Как в GNU grep, если совпадение
+// не является словом, проверяются более короткие совпадения с того же места,
+// а затем совпадения, начинающиеся дальше.
+func (m *matcher) findWord(line []byte, from int) []int {
+	for pos := from; pos <= len(line); {
+		loc := m.find(line, pos)
+		if loc == nil {
+			return nil
+		}
+		s, e := loc[0], loc[1]
+		if !isWordBefore(line, s) {
+			if !isWordAt(line, e) {
+				return []int{s, e}
+			}
+			// более короткие совпадения заканчиваются на границах символов
+			for end := e; ; {
+				_, size := utf8.DecodeLastRune(line[s:end])
+				if end -= size; end <= s {
+					break
+				}
+				if full, from := m.fullAt(line, s); !isWordAt(line, end) && full.Match(line[from:end]) {
+					return []int{s, end}
+				}
+			}
+		}
+		if s == len(line) {
+			return nil
+		}
+		_, size := utf8.DecodeRune(line[s:])
+		pos = s + size
+	}
+	return nil
+}
+
+// find возвращает границы первого совпадения, начинающегося не раньше позиции pos.
+// Как при поиске во всей строке, ^ совпадает только в ее начале, а \b учитывает
+// символ перед pos.
+func (m *matcher) find(line []byte, pos int) []int {
+	if pos == 0 || m.next == nil {
+		loc := m.re.FindIndex(line[pos:])
+		if loc == nil {
+			return nil
+		}
+		return []int{pos + loc[0], pos + loc[1]}
+	}
+	_, size := utf8.DecodeLastRune(line[:pos])
+	from := pos - size
+	loc := m.next.FindIndex(line[from:])
+	if loc == nil {
+		return nil
+	}
+	// совпадение начинается после символа, с которого начинается next
+	_, size = utf8.DecodeRune(line[from+loc[0]:])
+	return []int{from + loc[0] + size, from + loc[1]}
+}
+
+// fullAt возвращает выражение, совпадающее только с частью строки от позиции s,
+// и позицию, с которой эту часть нужно передать выражению
+func (m *matcher) fullAt(line []byte, s int) (finder, int) {
+	if s == 0 || m.fullNext == nil {
+		return m.full, s
+	}
+	_, size := utf8.DecodeLastRune(line[:s])
+	return m.fullNext, s - size
+}
+
+// Функция isWordRune сообщает, является ли r символом слова: буквой, цифрой или '_'
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// Функция isWordAt сообщает, начинается ли с позиции i строки символ слова
+func isWordAt(line []byte, i int) bool {
+	if i >= len(line) {
+		return false
+	}
+	r, _ := utf8.DecodeRune(line[i:])
+	return isWordRune(r)
+}
+
+// Функция isWordBefore сообщает, стоит ли перед позицией i строки символ слова
+func isWordBefore(line []byte, i int) bool {
+	if i == 0 {
+		return false
+	}
+	r, _ := utf8.DecodeLastRune(line[:i])
+	return isWordRune(r)
+}
@@ -0,0 +1,170 @@
+package grep
+
+import (
+	"reflect"
+	"testing"
+)
+
+// mustCompile возвращает matcher для патернов ps или завершает тест
+func mustCompile(t *testing.T, ps []string, o MatchOptions) *matcher {
+	t.Helper()
+	m, err := Compile(ps, o)
+	if err != nil {
+		t.Fatalf("Compile(%q): %v", ps, err)
+	}
+	return m.(*matcher)
+}
+
+// testMatch проверяет, какие строки lines соответствуют патернам ps
+func testMatch(t *testing.T, ps []string, o MatchOptions, lines []string, expected []bool) {
+	t.Helper()
+	m := mustCompile(t, ps, o)
+	for i, line := range lines {
+		if res := m.Match([]byte(line)); res != expected[i] {
+			t.Errorf("Match(%q) with patterns %q = %v, expected %v", line, ps, res, expected[i])
+		}
+	}
+}
+
+func TestCompile(t *testing.T) {
+	if m := mustCompile(t, []string{"a"}, MatchOptions{}); m.String() != "a" {
+		t.Errorf("regexp = %q, expected \"a\"", m.String())
+	}
+	if _, err := Compile([]string{`\`}, MatchOptions{}); err == nil {
+		t.Error(`Compile("\\"): expected error`)
+	}
+	m := mustCompile(t, nil, MatchOptions{})
+	if m.Match([]byte("")) || m.FindAll([]byte("a")) != nil {
+		t.Error("Compile without patterns: expected no matches")
+	}
+}
+
+func TestMatchModes(t *testing.T) {
+	lines := []string{"a+b", "aab", "a|b", "b"}
+	testMatch(t, []string{"a+b"}, MatchOptions{}, lines, []bool{true, false, false, false})
+
+	o := MatchOptions{Syntax: Extended}
+	testMatch(t, []string{"a+b"}, o, lines, []bool{false, true, false, false})
+	testMatch(t, []string{"a|b"}, o, lines, []bool{true, true, true, true})
+
+	o.Syntax = Perl
+	testMatch(t, []string{`a\d?b`}, o, lines, []bool{false, true, false, false})
+
+	o.Syntax = Fixed
+	testMatch(t, []string{"a|b", `x\E`}, o, lines, []bool{false, false, true, false})
+}
+
+func TestMatchWord(t *testing.T) {
+	o := MatchOptions{Word: true}
+	lines := []string{"foobar foo", "foobar", "_foo", "foo-bar", "füfoo"}
+	testMatch(t, []string{"foo"}, o, lines, []bool{true, false, false, true, false})
+
+	// более короткое совпадение с того же места является словом
+	testMatch(t, []string{"foo.*"}, o, []string{"foo barx"}, []bool{true})
+	// пустой патерн совпадает со строкой без слов
+	testMatch(t, []string{""}, o, []string{"", "a", " - "}, []bool{true, false, true})
+
+	// при повторном поиске с середины строки ^ не совпадает, а \B учитывает предыдущий символ
+	ere := MatchOptions{Syntax: Extended, Word: true}
+	testMatch(t, []string{"^(-x-a|x)"}, ere, []string{"-x-a_", "x-a_"}, []bool{false, true})
+	testMatch(t, []string{`\Bx-a`}, ere, []string{"-x-a_ xx-a"}, []bool{false})
+
+	// совпадение не заканчивается внутри символа UTF-8
+	testMatch(t, []string{"."}, o, []string{"Привет", "П р"}, []bool{false, true})
+	testMatch(t, []string{"П."}, o, []string{"Привет", "Пр"}, []bool{false, true})
+
+	m := mustCompile(t, []string{"ab*"}, o)
+	if loc := m.findWord([]byte("abbc ab"), 0); loc == nil || loc[0] != 5 || loc[1] != 7 {
+		t.Errorf("findWord = %v, expected [5 7]", loc)
+	}
+}
+
+func TestMatchLine(t *testing.T) {
+	o := MatchOptions{Line: true, IgnoreCase: true}
+	testMatch(t, []string{"a", "b.*"}, o, []string{"A", "ab", "bcd", "xb"}, []bool{true, false, true, false})
+}
+
+func TestFindAllWord(t *testing.T) {
+	m := mustCompile(t, []string{"ab*"}, MatchOptions{Word: true})
+	res := m.FindAll([]byte("ab abbc abb a"))
+	if !reflect.DeepEqual(res, [][]int{{0, 2}, {8, 11}, {12, 13}}) {
+		t.Errorf("FindAll = %v, expected [[0 2] [8 11] [12 13]]", res)
+	}
+}
+
+func TestFindAllSubmatchWord(t *testing.T) {
+	m := mustCompile(t, []string{`\(a\)\(b*\)`}, MatchOptions{Word: true})
+	res := m.FindAllSubmatch([]byte("abc ab a"))
+	expected := [][]int{{4, 6, 4, 5, 5, 6}, {7, 8, 7, 8, 8, 8}}
+	if !reflect.DeepEqual(res, expected) {
+		t.Errorf("FindAllSubmatch = %v, expected %v", res, expected)
+	}
+}
+
+func TestFindAllLongest(t *testing.T) {
+	// как в GNU grep, из альтернатив с одного места выбирается самая длинная
+	cases := []struct {
+		ps       []string
+		o        MatchOptions
+		line     string
+		expected [][]int
+	}{
+		{[]string{"foo", "foobar"}, MatchOptions{}, "foobar foo", [][]int{{0, 6}, {7, 10}}},
+		{[]string{"fo|foob"}, MatchOptions{Syntax: Extended}, "foobar", [][]int{{0, 4}}},
+		{[]string{"foo", "foo_x"}, MatchOptions{Word: true}, "foo_x foo", [][]int{{0, 5}, {6, 9}}},
+		{[]string{"fo|foob"}, MatchOptions{Syntax: Perl}, "foobar", [][]int{{0, 2}}},
+		{[]string{`ba\{,2\}`}, MatchOptions{}, "baaa", [][]int{{0, 3}}},
+	}
+	for _, c := range cases {
+		m := mustCompile(t, c.ps, c.o)
+		if res := m.FindAll([]byte(c.line)); !reflect.DeepEqual(res, c.expected) {
+			t.Errorf("FindAll(%q) with patterns %q = %v, expected %v", c.line, c.ps, res, c.expected)
+		}
+	}
+}
+
+func TestFindAllWordUTF8(t *testing.T) {
+	m := mustCompile(t, []string{"[^a-z ]"}, MatchOptions{Word: true})
+	if res := m.FindAll([]byte("ПР ж")); !reflect.DeepEqual(res, [][]int{{5, 7}}) {
+		t.Errorf("FindAll = %v, expected [[5 7]]", res)
+	}
+}
+
+func TestFindAllUnicode(t *testing.T) {
+	// как в GNU grep в локали UTF-8, символы слова и классы POSIX включают буквы всех алфавитов
+	cases := []struct {
+		p        string
+		o        MatchOptions
+		line     string
+		expected [][]int
+	}{
+		{`\bмир\b`, MatchOptions{}, "Привет мир, мирный", [][]int{{13, 19}}},
+		{`\<м`, MatchOptions{}, "мир ммм", [][]int{{0, 2}, {7, 9}}},
+		{`р\>`, MatchOptions{}, "мир ррр", [][]int{{4, 6}, {11, 13}}},
+		{`м\<ир`, MatchOptions{}, "мир", nil},
+		{`и\B`, MatchOptions{}, "мир и", [][]int{{2, 4}}},
+		{`\bи`, MatchOptions{}, "мир и", [][]int{{7, 9}}},
+		{`\w\+`, MatchOptions{}, "Жёлтый_1 ²x", [][]int{{0, 14}, {17, 18}}},
+		{`\W\+`, MatchOptions{}, "аб, вг", [][]int{{4, 6}}},
+		{`[[:upper:]]\+`, MatchOptions{}, "ПрИВет", [][]int{{0, 2}, {4, 8}}},
+		{`[[:upper:]]`, MatchOptions{IgnoreCase: true}, "жЖ", [][]int{{0, 2}, {2, 4}}},
+		{`\bмир|мирный`, MatchOptions{Syntax: Extended}, "мирный", [][]int{{0, 12}}},
+		// в синтаксисе Perl \b, как в RE2, учитывает только символы ASCII
+		{`\bм|мир`, MatchOptions{Syntax: Perl}, "мир", [][]int{{0, 6}}},
+	}
+	for _, c := range cases {
+		m := mustCompile(t, []string{c.p}, c.o)
+		if res := m.FindAll([]byte(c.line)); !reflect.DeepEqual(res, c.expected) {
+			t.Errorf("FindAll(%q) with pattern %q = %v, expected %v", c.line, c.p, res, c.expected)
+		}
+	}
+
+	m := mustCompile(t, []string{`\(м\)\(и\)\b`}, MatchOptions{})
+	res := m.FindAllSubmatch([]byte("ми мир"))
+	if expected := [][]int{{0, 4, 0, 2, 2, 4}}; !reflect.DeepEqual(res, expected) {
+		t.Errorf("FindAllSubmatch = %v, expected %v", res, expected)
+	}
+	// -w и \b одинаково определяют границы слов
+	testMatch(t, []string{"мир"}, MatchOptions{Word: true}, []string{"мирный", "мир!"}, []bool{false, true})
+	testMatch(t, []string{`\bмир\b`}, MatchOptions{}, []string{"мирный", "мир!"}, []bool{false, true})
+}
@@ -0,0 +1,279 @@
+package grep
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+var errBackref = errors.New("back-references are not supported")
+var errTrailingBackslash = errors.New("trailing backslash (\\)")
+var errBracket = errors.New("unmatched [, [^, [:, [., or [=")
+var errBrace = errors.New("unmatched \\{")
+var errInterval = errors.New("invalid content of \\{\\}")
+
+// wordClass - символы слова, как в isWordRune: буквы, десятичные цифры и '_'
+const wordClass = `\p{L}\p{Nd}_`
+
+// unicodeClasses - классы символов POSIX, которые в RE2 содержат только символы ASCII,
+// а в GNU grep в локали UTF-8 - символы всех алфавитов
+var unicodeClasses = map[string]string{
+	"[:alpha:]": `\p{L}`,
+	"[:alnum:]": `\p{L}\p{Nd}`,
+	"[:upper:]": `\p{Lu}`,
+	"[:lower:]": `\p{Ll}`,
+	"[:punct:]": `\p{P}\p{S}\p{No}`,
+	"[:graph:]": `\p{L}\p{M}\p{N}\p{P}\p{S}`,
+	"[:print:]": `\p{L}\p{M}\p{N}\p{P}\p{S}\p{Zs}`,
+}
+
+// Функция translateBRE переводит базовое регулярное выражение POSIX (grep -G)
+// в синтаксис RE2. Как в GNU grep, \?, \+, \| и \{ \} являются операторами,
+// а ?, +, |, {, }, ( и ) - обычными символами.
+func translateBRE(p string) (string, error) {
+	var b strings.Builder
+	// start - позиция в начале выражения или группы, где * и ^ имеют особый смысл
+	start := true
+	for i := 0; i < len(p); {
+		c := p[i]
+		switch {
+		case c == '\\':
+			if i+1 == len(p) {
+				return "", errTrailingBackslash
+			}
+			switch n := p[i+1]; n {
+			case '(', '|':
+				b.WriteByte(n)
+				i += 2
+				start = true
+				continue
+			case '{':
+				if start {
+					// как в GNU grep, \{ в начале выражения - обычный символ
+					b.WriteString(`\{`)
+					break
+				}
+				end := strings.Index(p[i:], `\}`)
+				if end < 0 {
+					return "", errBrace
+				}
+				interval := "{" + p[i+2:i+end] + "}"
+				if intervalLen(interval) != len(interval) {
+					return "", errInterval
+				}
+				// \{,m\} в GNU grep означает {0,m}
+				b.WriteString(strings.Replace(interval, "{,", "{0,", 1))
+				i += end + 2
+				start = false
+				continue
+			case ')', '}', '+', '?':
+				b.WriteByte(n)
+			default:
+				s, size, err := translateEscape(p[i+1:])
+				if err != nil {
+					return "", err
+				}
+				b.WriteString(s)
+				i += 1 + size
+				start = false
+				continue
+			}
+			i += 2
+		case c == '[':
+			s, size, err := translateBracket(p[i:])
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(s)
+			i += size
+		case c == '*' && start:
+			b.WriteString(`\*`)
+			i++
+		case c == '^':
+			if start {
+				b.WriteByte('^')
+				i++
+				// после ^ в начале выражения * тоже обычный символ
+				continue
+			}
+			b.WriteString(`\^`)
+			i++
+		case c == '$':
+			rest := p[i+1:]
+			if rest == "" || strings.HasPrefix(rest, `\)`) || strings.HasPrefix(rest, `\|`) {
+				b.WriteByte('$')
+			} else {
+				b.WriteString(`\$`)
+			}
+			i++
+		case c == '.' || c == '*':
+			b.WriteByte(c)
+			i++
+		default:
+			_, size := utf8.DecodeRuneInString(p[i:])
+			b.WriteString(regexp.QuoteMeta(p[i : i+size]))
+			i += size
+		}
+		start = false
+	}
+	return b.String(), nil
+}
+
+// Функция translateERE переводит расширенное регулярное выражение POSIX (grep -E)
+// в синтаксис RE2
+func translateERE(p string) (string, error) {
+	var b strings.Builder
+	start := true
+	for i := 0; i < len(p); {
+		c := p[i]
+		switch {
+		case c == '\\':
+			if i+1 == len(p) {
+				return "", errTrailingBackslash
+			}
+			s, size, err := translateEscape(p[i+1:])
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(s)
+			i += 1 + size
+		case c == '[':
+			s, size, err := translateBracket(p[i:])
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(s)
+			i += size
+		case c == '(' || c == '|':
+			b.WriteByte(c)
+			i++
+			start = true
+			continue
+		case c == '^':
+			// после ^ повторения тоже относятся к обычному символу
+			b.WriteByte(c)
+			i++
+			continue
+		case (c == '*' || c == '+' || c == '?') && start:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+			i++
+		case c == '{':
+			if n := intervalLen(p[i:]); n > 0 && !start {
+				// {,m} в GNU grep означает {0,m}
+				b.WriteString(strings.Replace(p[i:i+n], "{,", "{0,", 1))
+				i += n
+			} else {
+				b.WriteString(`\{`)
+				i++
+			}
+		default:
+			_, size := utf8.DecodeRuneInString(p[i:])
+			b.WriteString(p[i : i+size])
+			i += size
+		}
+		start = false
+	}
+	return b.String(), nil
+}
+
+// Функция intervalLen возвращает длину интервала {n}, {n,}, {,m} или {n,m}
+// в начале s либо 0, если s начинается не с интервала
+func intervalLen(s string) int {
+	end := strings.IndexByte(s, '}')
+	if end < 0 {
+		return 0
+	}
+	body := s[1:end]
+	lo, hi, comma := strings.Cut(body, ",")
+	if body == "" || body == "," || !isDigits(lo) || comma && !isDigits(hi) {
+		return 0
+	}
+	return end + 1
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Функция translateEscape переводит экранированный символ в начале s
+// (без обратной косой черты) и возвращает результат и длину символа
+func translateEscape(s string) (string, int, error) {
+	c := s[0]
+	switch {
+	case c >= '1' && c <= '9':
+		return "", 0, errBackref
+	case c == '<':
+		return wordStart, 1, nil
+	case c == '>':
+		return wordEnd, 1, nil
+	case c == 'w':
+		return `[` + wordClass + `]`, 1, nil
+	case c == 'W':
+		return `[^` + wordClass + `]`, 1, nil
+	case strings.IndexByte("bBsS", c) >= 0:
+		return `\` + string(c), 1, nil
+	case c == '`':
+		return `\A`, 1, nil
+	case c == '\'':
+		return `\z`, 1, nil
+	}
+	_, size := utf8.DecodeRuneInString(s)
+	return regexp.QuoteMeta(s[:size]), size, nil
+}
+
+// Функция translateBracket переводит выражение в квадратных скобках POSIX в начале s
+// и возвращает результат и длину выражения. В POSIX обратная косая черта внутри скобок
+// обычный символ, а ] в начале выражения не закрывает его.
+func translateBracket(s string) (string, int, error) {
+	var b strings.Builder
+	b.WriteByte('[')
+	i := 1
+	if i < len(s) && s[i] == '^' {
+		b.WriteByte('^')
+		i++
+	}
+	if i < len(s) && s[i] == ']' {
+		b.WriteString(`\]`)
+		i++
+	}
+	for i < len(s) {
+		switch c := s[i]; {
+		case c == ']':
+			b.WriteByte(']')
+			return b.String(), i + 1, nil
+		case c == '[' && i+1 < len(s) && s[i+1] == ':':
+			end := strings.Index(s[i+2:], ":]")
+			if end < 0 {
+				return "", 0, errBracket
+			}
+			class := s[i : i+2+end+2]
+			if u, ok := unicodeClasses[class]; ok {
+				class = u
+			}
+			b.WriteString(class)
+			i += 2 + end + 2
+		case c == '[' && i+1 < len(s) && (s[i+1] == '=' || s[i+1] == '.'):
+			// класс эквивалентности [=a=] и символ сортировки [.a.] из одного символа
+			end := strings.Index(s[i+2:], string(s[i+1])+"]")
+			if end < 0 {
+				return "", 0, errBracket
+			}
+			b.WriteString(regexp.QuoteMeta(s[i+2 : i+2+end]))
+			i += 2 + end + 2
+		case c == '\\' || c == '[':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+			i++
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return "", 0, errBracket
+}
@@ -0,0 +1,68 @@
+package grep
+
+import (
+	"testing"
+)
+
+func TestTranslateBRE(t *testing.T) {
+	cases := map[string]string{
+		`a\+b`:         `a+b`,
+		`\(x\)*`:       `(x)*`,
+		`*a`:           `\*a`,
+		`^*a`:          `^\*a`,
+		`a|b`:          `a\|b`,
+		`a\|b`:         `a|b`,
+		`a+?`:          `a\+\?`,
+		`x\{2,3\}`:     `x{2,3}`,
+		`ba\{,2\}`:     `ba{0,2}`,
+		`\{1\}`:        `\{1}`,
+		`a^b$c$`:       `a\^b\$c$`,
+		`[]a]`:         `[\]a]`,
+		`[\]`:          `[\\]`,
+		`[[:alpha:]]`:  `[\p{L}]`,
+		`[^[:digit:]]`: `[^[:digit:]]`,
+		`\<w\>`:        wordStart + `w` + wordEnd,
+		`\w\W`:         `[\p{L}\p{Nd}_][^\p{L}\p{Nd}_]`,
+		`a.b`:          `a.b`,
+	}
+	for p, expected := range cases {
+		res, err := translateBRE(p)
+		if err != nil || res != expected {
+			t.Errorf("translateBRE(%q) = %q, %v, expected %q, nil", p, res, err, expected)
+		}
+	}
+}
+
+func TestTranslateERE(t *testing.T) {
+	cases := map[string]string{
+		`a+b`:     `a+b`,
+		`(x)*|y`:  `(x)*|y`,
+		`+a`:      `\+a`,
+		`(*a)`:    `(\*a)`,
+		`a{,2}`:   `a{0,2}`,
+		`a{2,3}`:  `a{2,3}`,
+		`a{x}`:    `a\{x}`,
+		`{1}`:     `\{1}`,
+		`\(a\)`:   `\(a\)`,
+		`[a-z]+$`: `[a-z]+$`,
+	}
+	for p, expected := range cases {
+		res, err := translateERE(p)
+		if err != nil || res != expected {
+			t.Errorf("translateERE(%q) = %q, %v, expected %q, nil", p, res, err, expected)
+		}
+	}
+}
+
+func TestTranslateErrors(t *testing.T) {
+	for _, p := range []string{`\(a\)\1`, `a\`, `[a`, `[[:alpha:]`, `a\{2`, `a\{x\}`, `a\{,\}`} {
+		if _, err := translateBRE(p); err == nil {
+			t.Errorf("translateBRE(%q): expected error", p)
+		}
+	}
+	for _, p := range []string{`(a)\1`, `a\`, `[a`} {
+		if _, err := translateERE(p); err == nil {
+			t.Errorf("translateERE(%q): expected error", p)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+// Package grep ищет строки, соответствующие патернам, по правилам утилиты grep из GNU:
+// базовые и расширенные регулярные выражения, фиксированные строки, поиск слов
+// и строк целиком, контекст вокруг найденных строк, ограничение числа найденных
+// строк и обработка двоичных входов. Найденные строки и строки контекста
+// передаются событиями в Sink, который решает, как их выводить.
+package grep
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// BinaryMode - обработка двоичного входа, то есть входа с нулевым байтом
+type BinaryMode int
+
+const (
+	// BinaryMatch ищет совпадения в двоичном входе, но передает найденные строки
+	// с признаком Binary, а строки контекста не передает
+	BinaryMatch BinaryMode = iota
+	// BinaryText обрабатывает двоичный вход как текст (-a)
+	BinaryText
+	// BinaryNoMatch считает, что в двоичном входе нет совпадений (-I)
+	BinaryNoMatch
+)
+
+// Options описывает параметры поиска. Нулевое значение, кроме Matcher, соответствует
+// запуску grep без флагов: найденные строки без контекста и без ограничения числа.
+type Options struct {
+	// Matcher находит совпадения, обычно его возвращает Compile
+	Matcher Matcher
+	// Invert выбирает строки, не соответствующие патернам (-v)
+	Invert bool
+	// Before и After - число строк контекста перед найденными строками и после них (-B, -A)
+	Before int
+	After  int
+	// MaxCount останавливает поиск после MaxCount найденных строк (-m), 0 - без ограничения.
+	// Как в GNU grep, после последней из них передается только контекст,
+	// даже если в нем есть совпадения.
+	MaxCount int
+	// Binary - обработка двоичного входа
+	Binary BinaryMode
+}
+
+// Line - строка входа, передаваемая в Sink. Text действительна только до возврата
+// из метода Sink и не должна изменяться.
+type Line struct {
+	// Number - номер строки, начиная с 1, Offset - смещение ее начала во входе в байтах
+	Number int
+	Offset int64
+	// Text - строка без перевода строки
+	Text []byte
+	// Binary - найденная строка двоичного входа, которую не следует выводить как текст
+	Binary bool
+}
+
+// Sink получает найденные строки и строки контекста в порядке входа.
+// Ошибка, возвращенная методом Sink, останавливает поиск, а ErrStop
+// останавливает его без ошибки, например после первой найденной строки.
+type Sink interface {
+	Match(l Line) error
+	Context(l Line) error
+}
+
+// Flusher реализует Sink, накапливающий вывод. Search вызывает Flush перед
+// ожиданием данных входа, чтобы строки бесконечного входа вроде tail -f
+// выводились сразу.
+type Flusher interface {
+	Flush() error
+}
+
+// ErrStop возвращается методом Sink, чтобы остановить поиск без ошибки
+var ErrStop = errors.New("grep: search stopped")
+
+// Result - итоги поиска во входе
+type Result struct {
+	// Matches - число найденных строк, переданных в Sink.Match
+	Matches int
+	// Binary - во входе встретился нулевой байт, с BinaryText всегда false
+	Binary bool
+}
+
+// Search построчно ищет в r строки, соответствующие opts.Matcher, и передает
+// их вместе со строками контекста в sink. В памяти хранятся только текущая строка
+// и не более opts.Before строк контекста. Поиск останавливается при отмене ctx,
+// но не прерывает ожидание данных r.
+func Search(ctx context.Context, r io.Reader, opts Options, sink Sink) (Result, error) {
+	if opts.Matcher == nil {
+		return Result{}, errors.New("grep: nil Matcher")
+	}
+	if opts.Before < 0 || opts.After < 0 {
+		return Result{}, errors.New("invalid context length argument")
+	}
+	if opts.MaxCount < 0 {
+		return Result{}, fmt.Errorf("invalid max count: %d", opts.MaxCount)
+	}
+	s := &searcher{opts: opts, sink: sink, before: newRing(opts.Before)}
+	s.flusher, _ = sink.(Flusher)
+	err := s.search(ctx, r)
+	if err == ErrStop {
+		err = nil
+	}
+	return s.res, err
+}
+
+// lineReader читает строки произвольной длины. Возвращаемая строка
+// действительна до следующего вызова next.
+type lineReader struct {
+	r   *bufio.Reader
+	buf []byte
+}
+
+func newLineReader(r io.Reader) *lineReader {
+	return &lineReader{r: bufio.NewReader(r)}
+}
+
+// next возвращает очередную строку без перевода строки или io.EOF в конце входа
+func (lr *lineReader) next() ([]byte, error) {
+	lr.buf = lr.buf[:0]
+	for {
+		s, err := lr.r.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			// строка длиннее буфера bufio.Reader накапливается в lr.buf
+			lr.buf = append(lr.buf, s...)
+			continue
+		}
+		line := s
+		if len(lr.buf) > 0 {
+			lr.buf = append(lr.buf, s...)
+			line = lr.buf
+		}
+		if err != nil && (err != io.EOF || len(line) == 0) {
+			return nil, err
+		}
+		return bytes.TrimSuffix(line, []byte{'\n'}), nil
+	}
+}
+
+// ring хранит последние строки для передачи контекста перед совпадением (-B)
+type ring struct {
+	lines []Line
+	start int
+	n     int
+}
+
+func newRing(size int) *ring {
+	return &ring{lines: make([]Line, size)}
+}
+
+// push сохраняет копию строки l, вытесняя самую старую строку
+func (r *ring) push(l Line) {
+	if len(r.lines) == 0 {
+		return
+	}
+	i := (r.start + r.n) % len(r.lines)
+	if r.n == len(r.lines) {
+		r.start = (r.start + 1) % len(r.lines)
+	} else {
+		r.n++
+	}
+	// память строк переиспользуется, поэтому буфер ограничен B самыми длинными строками
+	text := append(r.lines[i].Text[:0], l.Text...)
+	r.lines[i] = l
+	r.lines[i].Text = text
+}
+
+// drain передает сохраненные строки в f, начиная с самой старой, и очищает буфер.
+// Ошибка f прерывает передачу.
+func (r *ring) drain(f func(Line) error) error {
+	n := r.n
+	r.n = 0
+	for k := 0; k < n; k++ {
+		if err := f(r.lines[(r.start+k)%len(r.lines)]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// searcher хранит состояние поиска в одном входе
+type searcher struct {
+	opts    Options
+	sink    Sink
+	flusher Flusher
+	before  *ring
+	// left - сколько строк контекста осталось передать после совпадения (-A)
+	left int
+	res  Result
+}
+
+// search ищет строки r и передает их в s.sink. Как в GNU grep, строки двоичного
+// входа не передаются как текст: найденные передаются с признаком Binary,
+// а контекст не передается. После MaxCount найденных строк передается
+// только контекст после последней из них.
+func (s *searcher) search(ctx context.Context, r io.Reader) error {
+	lr := newLineReader(r)
+	if s.opts.Binary != BinaryText {
+		// проверяется только уже прочитанная часть входа, чтобы не ждать данных
+		lr.r.Peek(1)
+		head, _ := lr.r.Peek(lr.r.Buffered())
+		s.res.Binary = bytes.IndexByte(head, 0) >= 0
+	}
+	done := ctx.Done()
+	var off int64
+	for num := 1; ; num++ {
+		select {
+		case <-done:
+			return ctx.Err()
+		default:
+		}
+		// перед ожиданием данных накопленный вывод сбрасывается
+		if lr.r.Buffered() == 0 && s.flusher != nil {
+			if err := s.flusher.Flush(); err != nil {
+				return err
+			}
+		}
+		text, err := lr.next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		l := Line{Number: num, Offset: off, Text: text}
+		off += int64(len(text)) + 1
+
+		if s.opts.Binary != BinaryText && !s.res.Binary && bytes.IndexByte(text, 0) >= 0 {
+			s.res.Binary = true
+			// контекст вокруг строк двоичного входа не передается
+			s.left = 0
+			s.before.drain(func(Line) error { return nil })
+		}
+		if s.res.Binary && s.opts.Binary == BinaryNoMatch {
+			return nil
+		}
+
+		if s.opts.MaxCount > 0 && s.res.Matches == s.opts.MaxCount {
+			if s.left <= 0 {
+				return nil
+			}
+			if err := s.sink.Context(l); err != nil {
+				return err
+			}
+			s.left--
+			continue
+		}
+
+		switch {
+		case s.opts.Matcher.Match(text) != s.opts.Invert:
+			s.res.Matches++
+			if s.res.Binary {
+				l.Binary = true
+			} else {
+				if err := s.before.drain(s.sink.Context); err != nil {
+					return err
+				}
+				s.left = s.opts.After
+			}
+			if err := s.sink.Match(l); err != nil {
+				return err
+			}
+			if s.res.Matches == s.opts.MaxCount && s.left == 0 {
+				return nil
+			}
+		case s.left > 0:
+			if err := s.sink.Context(l); err != nil {
+				return err
+			}
+			s.left--
+		case !s.res.Binary:
+			s.before.push(l)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package grep
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// recorder запоминает события поиска в виде "m3:text" для найденных строк,
+// "c3:text" для строк контекста и "b3" для найденных строк двоичного входа
+type recorder struct {
+	events  []string
+	stopAt  int
+	flushes int
+}
+
+func (r *recorder) Match(l Line) error {
+	if l.Binary {
+		r.events = append(r.events, fmt.Sprintf("b%d", l.Number))
+	} else {
+		r.events = append(r.events, fmt.Sprintf("m%d:%s", l.Number, l.Text))
+	}
+	if len(r.events) == r.stopAt {
+		return ErrStop
+	}
+	return nil
+}
+
+func (r *recorder) Context(l Line) error {
+	r.events = append(r.events, fmt.Sprintf("c%d:%s", l.Number, l.Text))
+	return nil
+}
+
+func (r *recorder) Flush() error {
+	r.flushes++
+	return nil
+}
+
+// search ищет строки r патерном "a" с параметрами opts и возвращает события
+func search(t *testing.T, r io.Reader, opts Options) ([]string, Result) {
+	t.Helper()
+	opts.Matcher = mustCompile(t, []string{"a"}, MatchOptions{})
+	var rec recorder
+	res, err := Search(context.Background(), r, opts, &rec)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	return rec.events, res
+}
+
+// testSearch ищет строки input патерном "a" с параметрами opts и сравнивает события с expected
+func testSearch(t *testing.T, input string, opts Options, expected ...string) Result {
+	t.Helper()
+	events, res := search(t, strings.NewReader(input), opts)
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("Search(%q, %+v) events = %q, expected %q", input, opts, events, expected)
+	}
+	return res
+}
+
+func TestSearch(t *testing.T) {
+	res := testSearch(t, "a\nb\nba", Options{}, "m1:a", "m3:ba")
+	if res != (Result{Matches: 2}) {
+		t.Errorf("Result = %+v, expected {Matches:2}", res)
+	}
+	testSearch(t, "a\nb", Options{Invert: true}, "m2:b")
+}
+
+func TestSearchContext(t *testing.T) {
+	input := "1\n2\n3a\n4\n5\n6\n7a\n8a\n9\n10\n"
+	testSearch(t, input, Options{Before: 2, After: 1},
+		"c1:1", "c2:2", "m3:3a", "c4:4", "c5:5", "c6:6", "m7:7a", "m8:8a", "c9:9")
+	testSearch(t, input, Options{Before: 5},
+		"c1:1", "c2:2", "m3:3a", "c4:4", "c5:5", "c6:6", "m7:7a", "m8:8a")
+}
+
+func TestSearchMaxCount(t *testing.T) {
+	// контекст после последней найденной строки передается, даже если в нем есть совпадения
+	testSearch(t, "a1\nb\na2\na3\nb\n", Options{MaxCount: 1, After: 2}, "m1:a1", "c2:b", "c3:a2")
+	testSearch(t, "a1\nb\na2\na3\n", Options{MaxCount: 2}, "m1:a1", "m3:a2")
+}
+
+func TestSearchBinary(t *testing.T) {
+	// нулевой байт в начале входа обнаруживается до первой строки
+	res := testSearch(t, "a\nb\n\x00\na\n", Options{After: 1}, "b1", "b4")
+	if res != (Result{Matches: 2, Binary: true}) {
+		t.Errorf("Result = %+v, expected {Matches:2 Binary:true}", res)
+	}
+	testSearch(t, "a\x00\n", Options{Binary: BinaryNoMatch})
+	testSearch(t, "a\nb\n\x00\na\n", Options{Binary: BinaryText, After: 1}, "m1:a", "c2:b", "m4:a")
+
+	// после обнаружения нулевого байта контекст не передается
+	newInput := func() io.Reader {
+		return io.MultiReader(strings.NewReader("a\nb\n"), strings.NewReader("\x00\nb\na\nb\n"))
+	}
+	expected := []string{"m1:a", "c2:b", "b5"}
+	if events, _ := search(t, newInput(), Options{Before: 1, After: 2}); !reflect.DeepEqual(events, expected) {
+		t.Errorf("events = %q, expected %q", events, expected)
+	}
+	expected = []string{"m1:a"}
+	if events, _ := search(t, newInput(), Options{Binary: BinaryNoMatch}); !reflect.DeepEqual(events, expected) {
+		t.Errorf("events with BinaryNoMatch = %q, expected %q", events, expected)
+	}
+}
+
+func TestSearchStop(t *testing.T) {
+	m := mustCompile(t, []string{"a"}, MatchOptions{})
+	rec := recorder{stopAt: 1}
+	res, err := Search(context.Background(), strings.NewReader("a\na\n"), Options{Matcher: m}, &rec)
+	if err != nil || res.Matches != 1 || len(rec.events) != 1 {
+		t.Errorf("Search with ErrStop = %+v, %v, events %q, expected 1 match", res, err, rec.events)
+	}
+	// перед ожиданием данных после прочитанной части входа вывод сбрасывается
+	rec = recorder{}
+	Search(context.Background(), strings.NewReader("a\na\n"), Options{Matcher: m}, &rec)
+	if rec.flushes == 0 {
+		t.Error("Search did not flush the sink")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Search(ctx, strings.NewReader("a\n"), Options{Matcher: m}, &recorder{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Search with a canceled context: %v, expected context.Canceled", err)
+	}
+
+	for _, opts := range []Options{{}, {Matcher: m, Before: -1}, {Matcher: m, MaxCount: -1}} {
+		if _, err := Search(context.Background(), strings.NewReader("a\n"), opts, &recorder{}); err == nil {
+			t.Errorf("Search with %+v: expected error", opts)
+		}
+	}
+}
+
+func TestLineReader(t *testing.T) {
+	long := strings.Repeat("x", 100000)
+	lr := newLineReader(strings.NewReader("a\n\n" + long + "\nb\r\nlast"))
+	expected := []string{"a", "", long, "b\r", "last"}
+	for _, e := range expected {
+		line, err := lr.next()
+		if err != nil || string(line) != e {
+			t.Fatalf("next() = %.20q, %v, expected %.20q, nil", line, err, e)
+		}
+	}
+	if _, err := lr.next(); err != io.EOF {
+		t.Errorf("next() at end: %v, expected io.EOF", err)
+	}
+}
+
+func TestRing(t *testing.T) {
+	r := newRing(2)
+	for i, s := range []string{"a", "b", "c"} {
+		r.push(Line{Number: i + 1, Offset: int64(2 * i), Text: []byte(s)})
+	}
+	var res []string
+	r.drain(func(l Line) error {
+		res = append(res, fmt.Sprint(l.Number, l.Offset, string(l.Text)))
+		return nil
+	})
+	if !reflect.DeepEqual(res, []string{"2 2b", "3 4c"}) {
+		t.Errorf("ring = %q, expected [2 2b 3 4c]", res)
+	}
+	r.drain(func(Line) error {
+		t.Error("ring is not empty after drain")
+		return nil
+	})
+}
@@ -0,0 +1,288 @@
+package grep
+
+import (
+	"regexp"
+	"regexp/syntax"
+	"unicode/utf8"
+)
+
+// В RE2 нет операторов начала и конца слова, поэтому \< и \> переводятся
+// в начало и конец строки многострочного режима: строки, в которых ищет grep,
+// не содержат '\n', и в переведенных выражениях эти операторы больше не встречаются.
+const (
+	wordStart = `(?m:^)`
+	wordEnd   = `(?m:$)`
+)
+
+// submatchFinder - finder, находящий также группы захвата.
+// Его реализуют *regexp.Regexp и wordRegexp.
+type submatchFinder interface {
+	finder
+	FindSubmatchIndex(b []byte) []int
+	FindAllSubmatchIndex(b []byte, n int) [][]int
+}
+
+// Функция compileRegexp компилирует выражение RE2. В RE2 \b и \B учитывают
+// только символы слова ASCII, поэтому с unicode выражения с границами слов
+// выполняются wordRegexp, где символы слова определяет isWordRune.
+// С longest из совпадений с одного места выбирается самое длинное.
+func compileRegexp(expr string, longest, unicode bool) (submatchFinder, error) {
+	if unicode {
+		re, err := syntax.Parse(expr, syntax.Perl)
+		if err != nil {
+			return nil, err
+		}
+		if hasWordBoundary(re) {
+			prog, err := syntax.Compile(re.Simplify())
+			if err != nil {
+				return nil, err
+			}
+			return &wordRegexp{expr: expr, prog: prog, longest: longest}, nil
+		}
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	if longest {
+		re.Longest()
+	}
+	return re, nil
+}
+
+// Функция hasWordBoundary сообщает, есть ли в выражении границы слов
+func hasWordBoundary(re *syntax.Regexp) bool {
+	switch re.Op {
+	case syntax.OpWordBoundary, syntax.OpNoWordBoundary, syntax.OpBeginLine, syntax.OpEndLine:
+		return true
+	}
+	for _, sub := range re.Sub {
+		if hasWordBoundary(sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// wordRegexp выполняет программу регулярного выражения параллельным перебором
+// состояний (pike VM), как regexp, но проверяет границы слов по isWordRune:
+// EmptyWordBoundary и EmptyNoWordBoundary - \b и \B, EmptyBeginLine и
+// EmptyEndLine - начало (\<) и конец (\>) слова.
+type wordRegexp struct {
+	expr    string
+	prog    *syntax.Prog
+	longest bool
+}
+
+// thread - состояние перебора: инструкция и границы групп захвата
+type thread struct {
+	pc  uint32
+	cap []int
+}
+
+// queue - упорядоченное по приоритету множество состояний на одной позиции строки
+type queue struct {
+	seen    []bool
+	threads []thread
+}
+
+func (q *queue) clear() {
+	for i := range q.seen {
+		q.seen[i] = false
+	}
+	q.threads = q.threads[:0]
+}
+
+// Функция emptyFlags возвращает условия нулевой длины, выполненные между
+// символами r1 и r2; -1 означает начало или конец строки
+func emptyFlags(r1, r2 rune) syntax.EmptyOp {
+	var op syntax.EmptyOp
+	if r1 < 0 {
+		op |= syntax.EmptyBeginText
+	}
+	if r2 < 0 {
+		op |= syntax.EmptyEndText
+	}
+	w1, w2 := r1 >= 0 && isWordRune(r1), r2 >= 0 && isWordRune(r2)
+	switch {
+	case !w1 && w2:
+		op |= syntax.EmptyWordBoundary | syntax.EmptyBeginLine
+	case w1 && !w2:
+		op |= syntax.EmptyWordBoundary | syntax.EmptyEndLine
+	default:
+		op |= syntax.EmptyNoWordBoundary
+	}
+	return op
+}
+
+// add добавляет в q состояние pc на позиции i и все состояния,
+// достижимые из него без чтения символа
+func (re *wordRegexp) add(q *queue, pc uint32, i int, cap []int, flags syntax.EmptyOp) {
+	if q.seen[pc] {
+		return
+	}
+	q.seen[pc] = true
+	inst := &re.prog.Inst[pc]
+	switch inst.Op {
+	case syntax.InstAlt, syntax.InstAltMatch:
+		re.add(q, inst.Out, i, cap, flags)
+		re.add(q, inst.Arg, i, cap, flags)
+	case syntax.InstEmptyWidth:
+		if syntax.EmptyOp(inst.Arg)&^flags == 0 {
+			re.add(q, inst.Out, i, cap, flags)
+		}
+	case syntax.InstNop:
+		re.add(q, inst.Out, i, cap, flags)
+	case syntax.InstCapture:
+		if int(inst.Arg) < len(cap) {
+			old := cap[inst.Arg]
+			cap[inst.Arg] = i
+			re.add(q, inst.Out, i, cap, flags)
+			cap[inst.Arg] = old
+		} else {
+			re.add(q, inst.Out, i, cap, flags)
+		}
+	case syntax.InstMatch, syntax.InstRune, syntax.InstRune1, syntax.InstRuneAny, syntax.InstRuneAnyNotNL:
+		q.threads = append(q.threads, thread{pc, append([]int(nil), cap...)})
+	}
+}
+
+// exec возвращает границы первого совпадения, начинающегося не раньше позиции pos,
+// и его групп захвата или nil. Символ перед pos учитывается границами слов.
+func (re *wordRegexp) exec(b []byte, pos int) []int {
+	n := len(re.prog.Inst)
+	clist := &queue{seen: make([]bool, n)}
+	nlist := &queue{seen: make([]bool, n)}
+	seed := make([]int, re.prog.NumCap)
+	var best []int
+
+	r1 := rune(-1)
+	if pos > 0 {
+		r1, _ = utf8.DecodeLastRune(b[:pos])
+	}
+	r2, w := rune(-1), 0
+	if pos < len(b) {
+		r2, w = utf8.DecodeRune(b[pos:])
+	}
+	for i := pos; ; {
+		flags := emptyFlags(r1, r2)
+		if best == nil {
+			// совпадение, начинающееся здесь, менее приоритетно уже начатых
+			for k := range seed {
+				seed[k] = -1
+			}
+			seed[0] = i
+			re.add(clist, uint32(re.prog.Start), i, seed, flags)
+		}
+		if len(clist.threads) == 0 && best != nil {
+			break
+		}
+
+		r3, w3 := rune(-1), 0
+		if i+w < len(b) {
+			r3, w3 = utf8.DecodeRune(b[i+w:])
+		}
+		next := emptyFlags(r2, r3)
+		for _, t := range clist.threads {
+			inst := &re.prog.Inst[t.pc]
+			if inst.Op == syntax.InstMatch {
+				if !re.longest || best == nil || (t.cap[0] < best[0] || t.cap[0] == best[0] && i > best[1]) {
+					t.cap[1] = i
+					best = t.cap
+				}
+				if !re.longest {
+					// остальные состояния менее приоритетны найденного совпадения
+					break
+				}
+				continue
+			}
+			if r2 >= 0 && matchRune(inst, r2) {
+				re.add(nlist, inst.Out, i+w, t.cap, next)
+			}
+		}
+		if i >= len(b) {
+			break
+		}
+		clist, nlist = nlist, clist
+		nlist.clear()
+		i += w
+		r1, r2, w = r2, r3, w3
+	}
+	return best
+}
+
+// Функция matchRune сообщает, читает ли инструкция символ r
+func matchRune(inst *syntax.Inst, r rune) bool {
+	switch inst.Op {
+	case syntax.InstRuneAny:
+		return true
+	case syntax.InstRuneAnyNotNL:
+		return r != '\n'
+	}
+	return inst.MatchRune(r)
+}
+
+// Match сообщает, есть ли в b совпадение
+func (re *wordRegexp) Match(b []byte) bool {
+	return re.exec(b, 0) != nil
+}
+
+// FindIndex возвращает границы первого совпадения в b или nil
+func (re *wordRegexp) FindIndex(b []byte) []int {
+	if loc := re.exec(b, 0); loc != nil {
+		return loc[:2]
+	}
+	return nil
+}
+
+// FindSubmatchIndex возвращает границы первого совпадения в b и его групп захвата или nil
+func (re *wordRegexp) FindSubmatchIndex(b []byte) []int {
+	return re.exec(b, 0)
+}
+
+// FindAllIndex возвращает границы не более n (все при n < 0)
+// непересекающихся совпадений в b
+func (re *wordRegexp) FindAllIndex(b []byte, n int) [][]int {
+	res := re.FindAllSubmatchIndex(b, n)
+	for i := range res {
+		res[i] = res[i][:2]
+	}
+	return res
+}
+
+// FindAllSubmatchIndex возвращает границы не более n (все при n < 0)
+// непересекающихся совпадений в b и их групп захвата. Как в regexp,
+// пустое совпадение сразу после предыдущего совпадения пропускается.
+func (re *wordRegexp) FindAllSubmatchIndex(b []byte, n int) [][]int {
+	var res [][]int
+	for pos, prevEnd := 0, -1; pos <= len(b) && (n < 0 || len(res) < n); {
+		loc := re.exec(b, pos)
+		if loc == nil {
+			break
+		}
+		accept := true
+		if loc[1] == pos {
+			if loc[0] == prevEnd {
+				accept = false
+			}
+			if pos < len(b) {
+				_, size := utf8.DecodeRune(b[pos:])
+				pos += size
+			} else {
+				pos++
+			}
+		} else {
+			pos = loc[1]
+		}
+		prevEnd = loc[1]
+		if accept {
+			res = append(res, loc)
+		}
+	}
+	return res
+}
+
+// String возвращает исходное выражение
+func (re *wordRegexp) String() string {
+	return re.expr
+}
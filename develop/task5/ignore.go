@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ignoreRule - правило из файла .gitignore
+type ignoreRule struct {
+	pattern string
+	// negate - правило начинается с "!" и возвращает исключенный путь,
+	// dirOnly - правило заканчивается "/" и относится только к каталогам,
+	// anchored - правило содержит "/" и сопоставляется с путем от каталога .gitignore
+	negate, dirOnly, anchored bool
+}
+
+// ignoreList - правила .gitignore каталога dir вместе с правилами родительских каталогов
+type ignoreList struct {
+	dir    string
+	rules  []ignoreRule
+	parent *ignoreList
+}
+
+// Функция parseIgnoreRule разбирает строку файла .gitignore. Пустые строки
+// и комментарии не являются правилами.
+func parseIgnoreRule(line string) (ignoreRule, bool) {
+	line = strings.TrimSuffix(line, "\r")
+	// пробелы в конце игнорируются, если не экранированы
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+	if line == "" || line[0] == '#' {
+		return ignoreRule{}, false
+	}
+
+	var r ignoreRule
+	if line[0] == '!' {
+		r.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if strings.Contains(line, "/") {
+		r.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	if line == "" {
+		return ignoreRule{}, false
+	}
+	r.pattern = line
+	return r, true
+}
+
+// Функция loadIgnore читает файл .gitignore каталога dir. Если файла нет,
+// возвращается parent.
+func loadIgnore(dir string, parent *ignoreList) *ignoreList {
+	f, err := os.Open(filepath.Join(dir, ".gitignore"))
+	if err != nil {
+		return parent
+	}
+	defer f.Close()
+
+	l := &ignoreList{dir: dir, parent: parent}
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if r, ok := parseIgnoreRule(sc.Text()); ok {
+			l.rules = append(l.rules, r)
+		}
+	}
+	return l
+}
+
+// ignored сообщает, исключен ли путь p. Правила более глубоких каталогов
+// и более поздние правила одного файла имеют приоритет.
+func (l *ignoreList) ignored(p string, isDir bool) bool {
+	if l == nil {
+		return false
+	}
+	res := l.parent.ignored(p, isDir)
+	rel, err := filepath.Rel(l.dir, p)
+	if err != nil {
+		return res
+	}
+	rel = filepath.ToSlash(rel)
+	for _, r := range l.rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		if r.match(rel) {
+			res = !r.negate
+		}
+	}
+	return res
+}
+
+// match сообщает, соответствует ли правилу путь rel относительно каталога .gitignore
+func (r *ignoreRule) match(rel string) bool {
+	if !r.anchored {
+		// исключенные каталоги не обходятся, поэтому достаточно сравнить последний элемент пути
+		return matchSegment(r.pattern, path.Base(rel))
+	}
+	return matchGlob(strings.Split(r.pattern, "/"), strings.Split(rel, "/"))
+}
+
+// Функция matchGlob сопоставляет элементы пути с элементами шаблона,
+// где "**" соответствует любому числу элементов
+func matchGlob(pattern, segs []string) bool {
+	if len(pattern) == 0 {
+		return len(segs) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segs); i++ {
+			if matchGlob(pattern[1:], segs[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	return len(segs) > 0 && matchSegment(pattern[0], segs[0]) && matchGlob(pattern[1:], segs[1:])
+}
+
+func matchSegment(pattern, name string) bool {
+	ok, err := path.Match(pattern, name)
+	return ok && err == nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestParseIgnoreRule(t *testing.T) {
+	cases := []struct {
+		line     string
+		expected ignoreRule
+		ok       bool
+	}{
+		{"*.log", ignoreRule{pattern: "*.log"}, true},
+		{"!keep.log  ", ignoreRule{pattern: "keep.log", negate: true}, true},
+		{"build/", ignoreRule{pattern: "build", dirOnly: true}, true},
+		{"/root.txt", ignoreRule{pattern: "root.txt", anchored: true}, true},
+		{"docs/**/*.md", ignoreRule{pattern: "docs/**/*.md", anchored: true}, true},
+		{`\#hash`, ignoreRule{pattern: "#hash"}, true},
+		{"# comment", ignoreRule{}, false},
+		{"", ignoreRule{}, false},
+	}
+	for _, c := range cases {
+		r, ok := parseIgnoreRule(c.line)
+		if ok != c.ok || r != c.expected {
+			t.Errorf("parseIgnoreRule(%q) = %+v, %v, expected %+v, %v", c.line, r, ok, c.expected, c.ok)
+		}
+	}
+}
+
+func TestIgnored(t *testing.T) {
+	root := &ignoreList{dir: "repo"}
+	for _, line := range []string{"*.log", "!keep.log", "build/", "/top.txt", "docs/**/*.md"} {
+		r, _ := parseIgnoreRule(line)
+		root.rules = append(root.rules, r)
+	}
+	sub := &ignoreList{dir: "repo/sub", parent: root}
+	r, _ := parseIgnoreRule("!*.log")
+	sub.rules = append(sub.rules, r)
+
+	cases := []struct {
+		l     *ignoreList
+		path  string
+		isDir bool
+		ok    bool
+	}{
+		{root, "repo/a.log", false, true},
+		{root, "repo/keep.log", false, false},
+		{root, "repo/build", true, true},
+		{root, "repo/build", false, false},
+		{root, "repo/top.txt", false, true},
+		{root, "repo/x/top.txt", false, false},
+		{root, "repo/docs/a.md", false, true},
+		{root, "repo/docs/a/b/c.md", false, true},
+		{root, "repo/a.md", false, false},
+		{sub, "repo/sub/a.log", false, false},
+		{nil, "repo/a.log", false, false},
+	}
+	for _, c := range cases {
+		if res := c.l.ignored(c.path, c.isDir); res != c.ok {
+			t.Errorf("ignored(%q, %v) = %v, expected %v", c.path, c.isDir, res, c.ok)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"unicode/utf8"
+
+	"go-grep/grep"
+)
+
+// События вывода --json. Каждое событие выводится отдельной строкой JSON.
+// begin и end обрамляют строки файла и выводятся только для файлов,
+// в которых есть выводимые строки, summary завершает вывод.
+
+type jsonBegin struct {
+	Type string `json:"type"`
+	File string `json:"file"`
+}
+
+// jsonLine - найденная строка (match) или строка контекста (context).
+// Строка, не являющаяся корректным UTF-8, выводится в line_base64.
+type jsonLine struct {
+	Type       string         `json:"type"`
+	File       string         `json:"file"`
+	LineNumber int            `json:"line_number"`
+	Offset     int64          `json:"offset"`
+	Line       *string        `json:"line,omitempty"`
+	LineBase64 []byte         `json:"line_base64,omitempty"`
+	Submatches []jsonSubmatch `json:"submatches,omitempty"`
+}
+
+// jsonSubmatch - совпадение в строке с границами в байтах от начала строки
+// и группами захвата. Не участвовавшая в совпадении группа выводится как null.
+type jsonSubmatch struct {
+	Start  int          `json:"start"`
+	End    int          `json:"end"`
+	Text   string       `json:"text"`
+	Groups []*jsonGroup `json:"groups,omitempty"`
+}
+
+type jsonGroup struct {
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+	Text  string `json:"text"`
+}
+
+type jsonEnd struct {
+	Type    string `json:"type"`
+	File    string `json:"file"`
+	Matches int    `json:"matches"`
+	Binary  bool   `json:"binary,omitempty"`
+}
+
+type jsonSummary struct {
+	Type         string `json:"type"`
+	Files        int    `json:"files"`
+	FilesMatched int    `json:"files_matched"`
+	Matches      int    `json:"matches"`
+}
+
+// Функция newEncoder возвращает json.Encoder, не экранирующий символы HTML
+func newEncoder(w *bufio.Writer) *json.Encoder {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return enc
+}
+
+// printJSON выводит событие match или context для строки, предваряя первое
+// событие файла событием begin
+func (p *printer) printJSON(l grep.Line, selected bool) {
+	p.beginJSON()
+	e := jsonLine{Type: "context", File: p.name, LineNumber: l.Number, Offset: l.Offset}
+	if selected {
+		e.Type = "match"
+	}
+	line := l.Text
+	if utf8.Valid(line) {
+		text := string(line)
+		e.Line = &text
+	} else {
+		e.LineBase64 = line
+	}
+	// с флагом -v найденные строки не содержат совпадений
+	if selected && !invert {
+		for _, loc := range findAllSubmatch(p.m, line) {
+			sm := jsonSubmatch{Start: loc[0], End: loc[1], Text: string(line[loc[0]:loc[1]])}
+			for i := 2; i < len(loc); i += 2 {
+				var g *jsonGroup
+				if loc[i] >= 0 {
+					g = &jsonGroup{loc[i], loc[i+1], string(line[loc[i]:loc[i+1]])}
+				}
+				sm.Groups = append(sm.Groups, g)
+			}
+			e.Submatches = append(e.Submatches, sm)
+		}
+	}
+	p.enc.Encode(e)
+}
+
+// Функция findAllSubmatch возвращает совпадения в строке с группами захвата,
+// если m их находит, или без них
+func findAllSubmatch(m grep.Matcher, line []byte) [][]int {
+	if sm, ok := m.(grep.SubmatchMatcher); ok {
+		return sm.FindAllSubmatch(line)
+	}
+	return m.FindAll(line)
+}
+
+// beginJSON выводит событие begin, если оно еще не выведено
+func (p *printer) beginJSON() {
+	if !p.begun {
+		p.enc.Encode(jsonBegin{Type: "begin", File: p.name})
+		p.begun = true
+	}
+}
+
+// printJSONEnd выводит событие end, если для файла было выведено событие begin.
+// Строки двоичного файла не выводятся, поэтому для него с найденными строками
+// выводятся только begin и end.
+func (p *printer) printJSONEnd(res grep.Result) {
+	if res.Binary && res.Matches > 0 {
+		p.beginJSON()
+	}
+	if p.begun {
+		p.enc.Encode(jsonEnd{Type: "end", File: p.name, Matches: res.Matches, Binary: res.Binary})
+	}
+}
+
+// Функция printSummary выводит событие summary с итогами поиска
+func printSummary(w *bufio.Writer, st stats) error {
+	newEncoder(w).Encode(jsonSummary{"summary", st.files, st.matched, st.lines})
+	return w.Flush()
+}
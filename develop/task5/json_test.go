@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// searchJSON ищет строки input патерном p с флагом --json и возвращает события
+func searchJSON(t *testing.T, p, input string) []map[string]any {
+	t.Helper()
+	jsonOutput = true
+	defer func() { jsonOutput = false }()
+
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	m := mustCompile(t, p)
+	if _, err := newPrinter(w, m, "in", false).search(strings.NewReader(input)); err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	w.Flush()
+
+	var events []map[string]any
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var e map[string]any
+		if err := dec.Decode(&e); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		events = append(events, e)
+	}
+	return events
+}
+
+func TestSearchJSON(t *testing.T) {
+	after = 1
+	defer func() { after = -1 }()
+
+	events := searchJSON(t, `(\w)=(\d)?`, "x\na=1 b=\nc\n\xff=\n")
+	expected := []map[string]any{
+		{"type": "begin", "file": "in"},
+		{"type": "match", "file": "in", "line_number": 2.0, "offset": 2.0, "line": "a=1 b=",
+			"submatches": []any{
+				map[string]any{"start": 0.0, "end": 3.0, "text": "a=1", "groups": []any{
+					map[string]any{"start": 0.0, "end": 1.0, "text": "a"},
+					map[string]any{"start": 2.0, "end": 3.0, "text": "1"},
+				}},
+				map[string]any{"start": 4.0, "end": 6.0, "text": "b=", "groups": []any{
+					map[string]any{"start": 4.0, "end": 5.0, "text": "b"},
+					nil,
+				}},
+			}},
+		{"type": "context", "file": "in", "line_number": 3.0, "offset": 9.0, "line": "c"},
+		{"type": "end", "file": "in", "matches": 1.0},
+	}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("events:\n%v\nexpected:\n%v", events, expected)
+	}
+
+	// строка, не являющаяся UTF-8, выводится в base64
+	events = searchJSON(t, `=`, "\xff=\n")
+	if len(events) != 3 || events[1]["line_base64"] != "/z0=" || events[1]["line"] != nil {
+		t.Errorf("events for invalid UTF-8: %v", events)
+	}
+
+	// файл без найденных строк не выводит событий, двоичный - только begin и end
+	if events := searchJSON(t, `z`, "a\n"); len(events) != 0 {
+		t.Errorf("events without matches: %v", events)
+	}
+	events = searchJSON(t, `a`, "a\x00\n")
+	if len(events) != 2 || events[1]["binary"] != true || events[1]["matches"] != 1.0 {
+		t.Errorf("events for a binary file: %v", events)
+	}
+}
+
+func TestJSONSummary(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"a": "x\nx\n", "b": "y\n"})
+	defer setFlags(t, nil)
+	setFlags(t, []string{"--json", "x", filepath.Join(dir, "a"), filepath.Join(dir, "b")})
+
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	if matched, err := execute(flag.Args(), w); err != nil || !matched {
+		t.Fatalf("execute = %v, %v, expected true, nil", matched, err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	expected := `{"type":"summary","files":2,"files_matched":1,"matches":2}`
+	if len(lines) != 5 || lines[4] != expected {
+		t.Errorf("output:\n%s\nexpected 5 events ending with %s", buf.String(), expected)
+	}
+
+	setFlags(t, []string{"--json", "-c", "x", os.DevNull})
+	if _, err := execute(flag.Args(), w); err == nil {
+		t.Error("execute with --json -c: expected error")
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"go-grep/grep"
+)
+
+// printer реализует grep.Sink и выводит найденные строки и контекст
+// в формате, заданном флагами
+type printer struct {
+	m grep.Matcher
+	w *bufio.Writer
+	// name - имя файла, prefix - предварять им выводимые строки
+	name   string
+	prefix bool
+
+	// grouped - разделять группы строк, last - номер последней выведенной строки
+	grouped bool
+	last    int
+	// enc - вывод событий --json, begun - событие begin уже выведено
+	enc   *json.Encoder
+	begun bool
+}
+
+// Функция contextLines возвращает число строк контекста после и перед совпадением
+// с учетом -C и сообщает, нужно ли разделять группы строк. Как в GNU grep, группы
+// разделяются, если задан любой из флагов -A, -B, -C, даже с нулевым значением.
+func contextLines() (a, b int, grouped bool) {
+	a, b = maxInt(after, around), maxInt(before, around)
+	grouped = (a >= 0 || b >= 0) && !noGroupSeparator && !count && !listFiles && !listNonMatching && !jsonOutput
+	return maxInt(a, 0), maxInt(b, 0), grouped
+}
+
+// Функция searchOptions возвращает параметры поиска патернами m, заданные флагами.
+// С флагами -c, -l и -L строки не выводятся, поэтому контекст не нужен.
+func searchOptions(m grep.Matcher) grep.Options {
+	a, b, _ := contextLines()
+	if count || listFiles || listNonMatching {
+		a, b = 0, 0
+	}
+	opts := grep.Options{Matcher: m, Invert: invert, Before: b, After: a, MaxCount: maxInt(maxCount, 0)}
+	switch {
+	case text:
+		opts.Binary = grep.BinaryText
+	case noBinary:
+		opts.Binary = grep.BinaryNoMatch
+	}
+	return opts
+}
+
+// newPrinter возвращает printer, выводящий строки в w
+func newPrinter(w *bufio.Writer, m grep.Matcher, name string, prefix bool) *printer {
+	_, _, grouped := contextLines()
+	p := &printer{m: m, w: w, name: name, prefix: prefix, grouped: grouped}
+	if jsonOutput {
+		p.enc = newEncoder(w)
+	}
+	return p
+}
+
+// search ищет строки в r и возвращает число строк, соответствующих патернам.
+// С флагом --json строки выводятся событиями, обрамленными событиями begin и end.
+func (p *printer) search(r io.Reader) (int, error) {
+	// с -m 0 вход не читается
+	if maxCount == 0 {
+		return 0, nil
+	}
+	res, err := grep.Search(context.Background(), r, searchOptions(p.m), p)
+	if p.enc != nil {
+		p.printJSONEnd(res)
+	}
+	return res.Matches, err
+}
+
+// Match выводит найденную строку. С флагами -c, -l и -L строки не выводятся,
+// а с -l и -L поиск заканчивается на первой найденной строке. Как в GNU grep,
+// вместо строк двоичного файла в stderr выводится сообщение о совпадении.
+func (p *printer) Match(l grep.Line) error {
+	switch {
+	case listFiles || listNonMatching:
+		return grep.ErrStop
+	case count:
+	case l.Binary && p.enc != nil:
+		return grep.ErrStop
+	case l.Binary:
+		// сообщение выводится после уже найденных строк
+		p.w.Flush()
+		fmt.Fprintf(os.Stderr, "grep: %s: binary file matches\n", p.name)
+		return grep.ErrStop
+	default:
+		p.emit(l, true)
+	}
+	return nil
+}
+
+// Context выводит строку контекста
+func (p *printer) Context(l grep.Line) error {
+	p.emit(l, false)
+	return nil
+}
+
+// Flush сбрасывает вывод, чтобы grep работал с бесконечным входом вроде tail -f
+func (p *printer) Flush() error {
+	return p.w.Flush()
+}
+
+// emit выводит найденную строку или строку контекста, отделяя от предыдущей
+// выведенной строки разделителем групп, если между ними есть пропущенные строки.
+// С флагом -o строки контекста не выводятся, но учитываются при разделении групп.
+func (p *printer) emit(l grep.Line, selected bool) {
+	if p.enc != nil {
+		p.printJSON(l, selected)
+		return
+	}
+	if p.grouped && p.last > 0 && l.Number > p.last+1 {
+		p.printSeparator()
+	}
+	p.last = l.Number
+	switch {
+	case !onlyMatching:
+		p.printLine(l, selected)
+	case selected:
+		p.printMatches(l)
+	}
+}
+
+// printSeparator выводит разделитель групп строк
+func (p *printer) printSeparator() {
+	p.w.WriteString(palette.wrap(palette.se, groupSeparator))
+	p.w.WriteByte('\n')
+}
+
+// printPrefix выводит перед строкой имя файла, если оно не пустое, номер строки,
+// если предоставлен флаг -n, и смещение в байтах, если предоставлен флаг -b,
+// отделяя их символом sep
+func (p *printer) printPrefix(num int, off int64, sep string) {
+	sep = palette.wrap(palette.se, sep)
+	if p.prefix {
+		p.w.WriteString(palette.wrap(palette.fn, p.name))
+		p.w.WriteString(sep)
+	}
+	if numerate {
+		p.w.WriteString(palette.wrap(palette.ln, strconv.Itoa(num)))
+		p.w.WriteString(sep)
+	}
+	if byteOffset {
+		p.w.WriteString(palette.wrap(palette.bn, strconv.FormatInt(off, 10)))
+		p.w.WriteString(sep)
+	}
+}
+
+// printLine выводит строку с префиксом. Строки, соответствующие патернам,
+// отделяются от префикса символом ":", остальные - символом "-".
+// Если вывод выделяется цветом, выделяются и все совпадения в строке.
+func (p *printer) printLine(l grep.Line, selected bool) {
+	sep := "-"
+	if selected {
+		sep = ":"
+	}
+	p.printPrefix(l.Number, l.Offset, sep)
+
+	line := l.Text
+	lineColor, matchColor := palette.lineColors(selected)
+	if lineColor == "" && matchColor == "" {
+		p.w.Write(line)
+		p.w.WriteByte('\n')
+		return
+	}
+	// как в GNU grep, цвет строки включается перед каждой частью между совпадениями,
+	// а выключается только после последней части
+	h := 0
+	if matchColor != "" {
+		for _, loc := range p.m.FindAll(line) {
+			if loc[0] == loc[1] {
+				continue
+			}
+			if lineColor != "" {
+				p.w.WriteString(palette.start(lineColor))
+			}
+			p.w.Write(line[h:loc[0]])
+			p.w.WriteString(palette.wrap(matchColor, string(line[loc[0]:loc[1]])))
+			h = loc[1]
+		}
+	}
+	p.w.WriteString(palette.wrap(lineColor, string(line[h:])))
+	p.w.WriteByte('\n')
+}
+
+// printMatches выводит каждое непустое совпадение в строке на отдельной строке (-o).
+// С флагом -b выводится смещение совпадения, а не строки.
+func (p *printer) printMatches(l grep.Line) {
+	for _, loc := range p.m.FindAll(l.Text) {
+		if loc[0] == loc[1] {
+			continue
+		}
+		p.printPrefix(l.Number, l.Offset+int64(loc[0]), ":")
+		p.w.WriteString(palette.wrap(palette.ms, string(l.Text[loc[0]:loc[1]])))
+		p.w.WriteByte('\n')
+	}
+}
@@ -5,9 +5,14 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
-	"regexp"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
+
+	"go-grep/grep"
 )
 
 /*
@@ -28,79 +33,159 @@ import (
 Программа должна проходить все тесты. Код должен проходить проверки go vet и golint.
 */
 
-var after uint64
-var before uint64
-var context uint64
+var after int
+var before int
+var around int
 var count bool
 var ignore bool
 var invert bool
 var fixed bool
 var numerate bool
+var basic bool
+var extended bool
+var perl bool
+var word bool
+var line bool
+var patterns stringList
+var patternFiles stringList
+var recursive bool
+var dereference bool
+var includes stringList
+var excludes stringList
+var excludeDirs stringList
+var noIgnore bool
+var withName bool
+var noName bool
+var listFiles bool
+var listNonMatching bool
+var noBinary bool
+var text bool
+var color colorMode
+var onlyMatching bool
+var byteOffset bool
+var maxCount int
+var groupSeparator string
+var noGroupSeparator bool
+var jsonOutput bool
+var decompress bool
 
 func init() {
 	testing.Init()
-	flag.Uint64Var(&after, "A", 0, "")
-	flag.Uint64Var(&before, "B", 0, "")
-	flag.Uint64Var(&context, "C", 0, "")
+	flag.IntVar(&after, "A", -1, "")
+	flag.IntVar(&before, "B", -1, "")
+	flag.IntVar(&around, "C", -1, "")
 	flag.BoolVar(&count, "c", false, "")
 	flag.BoolVar(&ignore, "i", false, "")
 	flag.BoolVar(&invert, "v", false, "")
 	flag.BoolVar(&fixed, "F", false, "")
 	flag.BoolVar(&numerate, "n", false, "")
+	flag.BoolVar(&basic, "G", false, "PATTERNS are basic regular expressions (default)")
+	flag.BoolVar(&extended, "E", false, "PATTERNS are extended regular expressions")
+	flag.BoolVar(&perl, "P", false, "PATTERNS are Perl-like (Go RE2) regular expressions")
+	flag.BoolVar(&word, "w", false, "match only whole words")
+	flag.BoolVar(&line, "x", false, "match only whole lines")
+	flag.Var(&patterns, "e", "use PATTERNS for matching, may be repeated")
+	flag.Var(&patternFiles, "f", "take PATTERNS from FILE, one per line, may be repeated")
+	flag.BoolVar(&recursive, "r", false, "search directories recursively, skipping symlinks inside them")
+	flag.BoolVar(&dereference, "R", false, "search directories recursively, following all symlinks")
+	flag.Var(&includes, "include", "search only files whose base name matches GLOB")
+	flag.Var(&excludes, "exclude", "skip files whose base name matches GLOB")
+	flag.Var(&excludeDirs, "exclude-dir", "skip directories whose base name matches GLOB")
+	flag.BoolVar(&noIgnore, "no-ignore", false, "do not skip files listed in .gitignore and .git directories")
+	flag.BoolVar(&withName, "H", false, "print the file name for each match")
+	flag.BoolVar(&noName, "h", false, "suppress the file name prefix on output")
+	flag.BoolVar(&listFiles, "l", false, "print only names of files with matches")
+	flag.BoolVar(&listNonMatching, "L", false, "print only names of files without matches")
+	flag.BoolVar(&noBinary, "I", false, "assume that binary files do not match")
+	flag.BoolVar(&text, "a", false, "process binary files as text")
+	flag.Var(&color, "color", "highlight matches: never, always or auto (GREP_COLORS sets the colors)")
+	flag.BoolVar(&onlyMatching, "o", false, "print only the matched parts of lines")
+	flag.BoolVar(&byteOffset, "b", false, "print the byte offset of each line or match")
+	flag.IntVar(&maxCount, "m", -1, "stop after NUM selected lines, -1 means no limit")
+	flag.StringVar(&groupSeparator, "group-separator", "--", "print SEP between groups of context lines")
+	flag.BoolVar(&noGroupSeparator, "no-group-separator", false, "do not separate groups of context lines")
+	flag.BoolVar(&jsonOutput, "json", false, "print results as JSON Lines events: begin, match, context, end and summary")
+	flag.BoolVar(&decompress, "z", false, "search gzip, bzip2 and zstd compressed files and files in tar and zip archives")
+	flag.BoolVar(&decompress, "decompress", false, "same as -z")
 	flag.Parse()
 }
 
-// parseArgs объединяет патерны в одно регулярное выражение с учетом -i -F,
-// возвращает regex и имя файла.
-func parseArgs(args []string) (re *regexp.Regexp, file string, err error) {
-	switch {
-	case len(args) == 0:
-		return nil, "", errors.New("no pattern specified")
-	case len(args) == 1:
-		ps := args[0]
-		if fixed {
-			ps = `\Q` + ps + `\E`
-		}
-		if ignore {
-			ps = `(?i)(` + ps + `)`
-		}
-		re, err := regexp.Compile(ps)
+// stringList реализует flag.Value и накапливает значения флага, переданного несколько раз
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+// parseArgs возвращает Matcher для патернов из флагов -e и -f, а если они не заданы -
+// из первого аргумента, и имена файлов из остальных аргументов.
+// Патерн, содержащий переводы строк, - это несколько патернов.
+func parseArgs(args []string) (m grep.Matcher, files []string, err error) {
+	opts, err := matchOptions()
+	if err != nil {
+		return nil, nil, err
+	}
+	var ps []string
+	for _, p := range patterns {
+		ps = append(ps, strings.Split(p, "\n")...)
+	}
+	for _, file := range patternFiles {
+		fps, err := readPatterns(file)
 		if err != nil {
-			return nil, "", err
-		}
-		return re, "", nil
-	default:
-		st, en := `(`, `)`
-		if fixed {
-			st, en = `(\Q`, `\E)`
+			return nil, nil, err
 		}
-		ps := st + args[0] + en
-		for i := 1; i < len(args)-1; i++ {
-			ps += `|` + st + args[i] + en
-		}
-		if ignore {
-			ps = `(?i)(` + ps + `)`
-		}
-		re, err := regexp.Compile(ps)
-		if err != nil {
-			return nil, "", err
+		ps = append(ps, fps...)
+	}
+	if len(patterns) == 0 && len(patternFiles) == 0 {
+		if len(args) == 0 {
+			return nil, nil, errors.New("no pattern specified")
 		}
-		return re, args[len(args)-1], nil
+		ps, args = strings.Split(args[0], "\n"), args[1:]
 	}
+
+	m, err = grep.Compile(ps, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m, args, nil
 }
 
-func maxUint64(a, b uint64) uint64 {
-	if a > b {
-		return a
+// Функция matchOptions возвращает синтаксис патернов, заданный одним из флагов -G -E -P -F,
+// и правила сопоставления -i -w -x
+func matchOptions() (grep.MatchOptions, error) {
+	opts := grep.MatchOptions{IgnoreCase: ignore, Word: word, Line: line}
+	modes := 0
+	for syntax, b := range map[grep.Syntax]bool{grep.Basic: basic, grep.Extended: extended, grep.Perl: perl, grep.Fixed: fixed} {
+		if b {
+			opts.Syntax = syntax
+			modes++
+		}
 	}
-	return b
+	if modes > 1 {
+		return opts, errors.New("conflicting matchers specified")
+	}
+	return opts, nil
 }
 
-func minInt(a, b int) int {
-	if a < b {
-		return a
+// readPatterns читает патерны из файла, по одному в строке. Имя "-" означает Stdin.
+func readPatterns(file string) ([]string, error) {
+	var data []byte
+	var err error
+	if file == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(file)
 	}
-	return b
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n"), nil
 }
 
 func maxInt(a, b int) int {
@@ -110,103 +195,131 @@ func maxInt(a, b int) int {
 	return b
 }
 
-type scanner struct {
-	sc *bufio.Scanner
-	f  *os.File
+// stats - итоги поиска в нескольких файлах
+type stats struct {
+	// files - число просмотренных файлов, matched - число файлов с найденными строками,
+	// lines - число найденных строк
+	files, matched, lines int
 }
 
-// newScanner возвращает сканер для работы с файлом,
-// если имя файла - пустая строка, то сканер работает с os.Stdin
-func newScanner(file string) (*scanner, error) {
-	if file == "" {
-		sc := bufio.NewScanner(os.Stdin)
-		return &scanner{sc: sc}, nil
+// add учитывает файл, в котором найдено n строк
+func (st *stats) add(n int) {
+	st.files++
+	st.lines += n
+	if n > 0 {
+		st.matched++
 	}
-	f, err := os.Open(file)
+}
+
+// grepFile ищет строки файла, соответствующие патернам, и выводит их в w,
+// предваряя именем файла, если prefix. Имя "-" означает Stdin.
+// Возвращает число найденных строк.
+func grepFile(w *bufio.Writer, file string, prefix bool, m grep.Matcher) (int, error) {
+	r, name, err := openFile(file)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
-	sc := bufio.NewScanner(f)
-	return &scanner{sc, f}, nil
-}
+	defer r.Close()
 
-// readLines возвращет слайс строк из файла как []byte
-func readLines(scan *scanner) (lines [][]byte) {
-	for scan.sc.Scan() {
-		lines = append(lines, scan.sc.Bytes())
+	if decompress {
+		return grepDecompressed(w, r, name, prefix, m)
 	}
-	return lines
+	return grepReader(w, r, name, prefix, m)
 }
 
-// filter возвращает номера строк, соответствущих регулярному
-// выражению с учетом инверсии
-func filter(lines [][]byte, re *regexp.Regexp) (s []int) {
-	for i, line := range lines {
-		if re.Match(line) != invert {
-			s = append(s, i)
-		}
+// grepReader ищет строки r, соответствующие патернам, и выводит их в w,
+// предваряя именем name, если prefix. Возвращает число найденных строк.
+func grepReader(w *bufio.Writer, r io.Reader, name string, prefix bool, m grep.Matcher) (int, error) {
+	n, err := newPrinter(w, m, name, prefix).search(r)
+	if err != nil {
+		return n, err
 	}
-	return s
-}
 
-// printLine печатает в Stdout строку, а также номер строки, если предоставлен флаг -n.
-// Строки, соответствубщие патернам, отделяются от номера символом ":", остальные - символом "-"
-func printLine(num int, line []byte, match bool) {
 	switch {
-	case numerate && match:
-		fmt.Printf("%d:%s\n", num, line)
-	case numerate && !match:
-		fmt.Printf("%d-%s\n", num, line)
-	default:
-		fmt.Println(string(line))
+	case listFiles:
+		if n > 0 {
+			fmt.Fprintln(w, name)
+		}
+	case listNonMatching:
+		if n == 0 {
+			fmt.Fprintln(w, name)
+		}
+	// Если предоставлен флаг -c, печатается только количество строк, соответствуюших патернам
+	case count:
+		if prefix {
+			fmt.Fprint(w, name, ":")
+		}
+		fmt.Fprintln(w, n)
 	}
+	return n, w.Flush()
 }
 
-// printLines печатает строки lines с номерами из n в соответствии со значениями,
-// переданными флагами -A, -B, -C.
-func printLines(lines [][]byte, n []int) {
-	h := 0
-	for i := 0; i < len(n)-1; i++ {
-		b := maxInt(h, n[i]-int(before))
-		a := minInt(n[i]+int(after), n[i+1]-1)
-		for j := b; j <= a; j++ {
-			printLine(j+1, lines[j], n[i] == j)
-		}
-		h = a + 1
+// Функция showNames сообщает, нужно ли предварять строки именами файлов:
+// при поиске в нескольких файлах или в каталоге, если это не изменено флагами -H и -h
+func showNames(files []string) bool {
+	if withName || noName {
+		return withName
+	}
+	if len(files) > 1 {
+		return true
 	}
-	b := maxInt(h, n[len(n)-1]-int(before))
-	a := minInt(n[len(n)-1]+int(after), len(lines)-1)
-	for j := b; j <= a; j++ {
-		printLine(j+1, lines[j], n[len(n)-1] == j)
+	if !recursive && !dereference {
+		return false
 	}
+	if len(files) == 0 {
+		return true
+	}
+	info, err := os.Stat(files[0])
+	return err == nil && info.IsDir()
 }
 
-func main() {
-
-	// флаги -A, -B, -C могут использоваться совместно
-	after = maxUint64(after, context)
-	before = maxUint64(before, context)
-
-	re, file, err := parseArgs(flag.Args())
+// Функция execute ищет строки по аргументам командной строки args
+// и выводит результат в w. Возвращает true, если найдена хотя бы одна строка.
+func execute(args []string, w *bufio.Writer) (bool, error) {
+	if after < -1 || before < -1 || around < -1 {
+		return false, errors.New("invalid context length argument")
+	}
+	if jsonOutput && (count || listFiles || listNonMatching) {
+		return false, errors.New("--json is incompatible with -c, -l and -L")
+	}
+	m, files, err := parseArgs(args)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		return
+		return false, err
 	}
 
-	scan, err := newScanner(file)
-	if err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		return
+	var st stats
+	prefix := showNames(files)
+	if !recursive && !dereference && len(files) <= 1 {
+		// единственный файл читается без пула, чтобы вывод не накапливался в памяти
+		file := "-"
+		if len(files) == 1 {
+			file = files[0]
+		}
+		if file == "-" || included(filepath.Base(file)) {
+			n, err := grepFile(w, file, prefix, m)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			} else {
+				st.add(n)
+			}
+		}
+	} else {
+		st = grepFiles(w, files, m, prefix, runtime.NumCPU())
 	}
-	defer scan.f.Close()
 
-	lines := readLines(scan)
-	filtered := filter(lines, re)
+	if jsonOutput {
+		if err := printSummary(w, st); err != nil {
+			return false, err
+		}
+	}
+	return st.lines > 0, nil
+}
 
-	// Если предоставлен флаг -c, печатается только количество строк, соответствуюших патернам
-	if count {
-		fmt.Println(len(filtered))
-	} else if len(filtered) > 0 {
-		printLines(lines, filtered)
+func main() {
+	if color.enabled() {
+		palette = parseColors(os.Getenv("GREP_COLORS"))
+	}
+	if _, err := execute(flag.Args(), bufio.NewWriter(os.Stdout)); err != nil {
+		fmt.Fprintln(os.Stderr, err)
 	}
 }
@@ -1,36 +1,44 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
 	"reflect"
-	"regexp"
+	"strings"
 	"testing"
+
+	"go-grep/grep"
 )
 
 func TestParseArgsNoPattern(t *testing.T) {
 	args := []string{}
-	re, file, err := parseArgs(args)
+	m, files, err := parseArgs(args)
 	if err == nil {
-		t.Logf("parseArgs(args) = %v, %q, %v, expected nil, \"\", error", *re, file, err)
+		t.Logf("parseArgs(args) = %v, %q, %v, expected nil, nil, error", m, files, err)
+		t.Fail()
 	}
 }
 
 func TestParseArgsNoFilename(t *testing.T) {
 	args := []string{"a"}
-	_, file, _ := parseArgs(args)
-	if file != "" {
-		t.Logf("filename = %s, expected \"\"", file)
+	_, files, _ := parseArgs(args)
+	if len(files) != 0 {
+		t.Logf("files = %q, expected none", files)
 		t.Fail()
 	}
 }
 
 func TestParseArgsCorrectPattern(t *testing.T) {
 	args := []string{"a"}
-	re, _, _ := parseArgs(args)
+	m, _, _ := parseArgs(args)
 
 	expected := "a"
 
-	if re.String() != expected {
-		t.Logf("regexp = %q, expected %q", re.String(), expected)
+	if fmt.Sprint(m) != expected {
+		t.Logf("regexp = %q, expected %q", fmt.Sprint(m), expected)
 		t.Fail()
 	}
 }
@@ -39,52 +47,146 @@ func TestParseArgsCorrectRawPattern(t *testing.T) {
 	fixed = true
 	defer func() { fixed = false }()
 	args := []string{"a"}
-	re, _, _ := parseArgs(args)
+	m, _, _ := parseArgs(args)
 
 	expected := `\Qa\E`
 
-	if re.String() != expected {
-		t.Logf("regexp = %q, expected %q", re.String(), expected)
+	if fmt.Sprint(m) != expected {
+		t.Logf("regexp = %q, expected %q", fmt.Sprint(m), expected)
 		t.Fail()
 	}
 }
 
 func TestParseArgsIncorrectPattern(t *testing.T) {
 	args := []string{`\`}
-	re, file, err := parseArgs(args)
+	m, files, err := parseArgs(args)
 	if err == nil {
-		t.Logf("parseArgs(args) = %v, %q, %v, expected nil, \"\", error", *re, file, err)
+		t.Logf("parseArgs(args) = %v, %q, %v, expected nil, nil, error", m, files, err)
 		t.Fail()
 	}
 }
 
-func TestFilter(t *testing.T) {
-	lines := [][]byte{[]byte("a"), []byte("b")}
-	re := regexp.MustCompile("a")
-
-	s := filter(lines, re)
-
-	expected := []int{0}
+// mustCompile возвращает Matcher для регулярного выражения RE2 p
+func mustCompile(t *testing.T, p string) grep.Matcher {
+	t.Helper()
+	m, err := grep.Compile([]string{p}, grep.MatchOptions{Syntax: grep.Perl})
+	if err != nil {
+		t.Fatalf("Compile(%q): %v", p, err)
+	}
+	return m
+}
 
-	if !reflect.DeepEqual(s, expected) {
-		t.Logf("filter result: %v, expected: %v", s, expected)
-		t.Fail()
+// testSearch ищет строки input патерном "a" и сравнивает вывод с expected
+func testSearch(t *testing.T, input, expected string) {
+	t.Helper()
+	m := mustCompile(t, "a")
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	if _, err := newPrinter(w, m, "", false).search(strings.NewReader(input)); err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	w.Flush()
+	if buf.String() != expected {
+		t.Errorf("search(%q) = %q, expected %q", input, buf.String(), expected)
 	}
 }
 
+func TestFilter(t *testing.T) {
+	testSearch(t, "a\nb", "a\n")
+}
+
 func TestFilterInverse(t *testing.T) {
 	invert = true
 	defer func() { invert = false }()
 
-	lines := [][]byte{[]byte("a"), []byte("b")}
-	re := regexp.MustCompile("a")
+	testSearch(t, "a\nb", "b\n")
+}
 
-	s := filter(lines, re)
+func TestSearchContext(t *testing.T) {
+	input := "1\n2\n3a\n4\n5\n6\n7a\n8a\n9\n10\n"
+	after, before, numerate = 1, 2, true
+	defer func() { after, before, numerate = -1, -1, false }()
 
-	expected := []int{1}
+	testSearch(t, input, "1-1\n2-2\n3:3a\n4-4\n5-5\n6-6\n7:7a\n8:8a\n9-9\n")
 
-	if !reflect.DeepEqual(s, expected) {
-		t.Logf("filter result: %v, expected: %v", s, expected)
-		t.Fail()
+	after, before = 0, 5
+	testSearch(t, input, "1-1\n2-2\n3:3a\n4-4\n5-5\n6-6\n7:7a\n8:8a\n")
+}
+
+func TestSearchOnlyMatching(t *testing.T) {
+	onlyMatching, byteOffset, after = true, true, 1
+	defer func() { onlyMatching, byteOffset, after = false, false, -1 }()
+
+	testSearch(t, "bab\nb\naaxa\n", "1:a\n6:a\n7:a\n9:a\n")
+}
+
+func TestSearchByteOffset(t *testing.T) {
+	byteOffset, before = true, 1
+	defer func() { byteOffset, before = false, -1 }()
+
+	testSearch(t, "b\nxyz\nab\n", "2-xyz\n6:ab\n")
+}
+
+func TestSearchCount(t *testing.T) {
+	count = true
+	defer func() { count = false }()
+
+	m := mustCompile(t, "a")
+	var buf bytes.Buffer
+	n, err := newPrinter(bufio.NewWriter(&buf), m, "", false).search(strings.NewReader("a\nb\nab"))
+	if n != 2 || err != nil || buf.Len() != 0 {
+		t.Errorf("search = %d, %v, output %q, expected 2, nil, no output", n, err, buf.String())
+	}
+}
+
+func TestParseArgsPatterns(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "patterns")
+	if err := os.WriteFile(file, []byte("x\ny\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	patterns = stringList{"a\nb"}
+	patternFiles = stringList{file}
+	defer func() { patterns, patternFiles = nil, nil }()
+
+	m, files, err := parseArgs([]string{"f1", "f2"})
+	if err != nil {
+		t.Fatalf("parseArgs: %v", err)
+	}
+	if !reflect.DeepEqual(files, []string{"f1", "f2"}) {
+		t.Errorf("files = %q, expected [f1 f2]", files)
+	}
+	for _, s := range []string{"a", "b", "x", "y"} {
+		if !m.Match([]byte(s)) {
+			t.Errorf("match(%q) = false, expected true", s)
+		}
+	}
+	if m.Match([]byte("f1")) {
+		t.Error(`match("f1") = true, expected false`)
+	}
+}
+
+func TestParseArgsEmptyPatternFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "patterns")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	patternFiles = stringList{file}
+	defer func() { patternFiles = nil }()
+
+	m, _, err := parseArgs(nil)
+	if err != nil {
+		t.Fatalf("parseArgs: %v", err)
+	}
+	if m.Match([]byte("")) || m.Match([]byte("a")) {
+		t.Error("empty pattern file: expected no matches")
+	}
+}
+
+func TestConflictingMatchers(t *testing.T) {
+	extended, fixed = true, true
+	defer func() { extended, fixed = false, false }()
+
+	if _, _, err := parseArgs([]string{"a"}); err == nil {
+		t.Error("parseArgs with -E and -F: expected error")
 	}
 }
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"go-grep/grep"
+)
+
+// stdinName - имя, под которым выводится стандартный ввод
+const stdinName = "(standard input)"
+
+var errLoop = errors.New("warning: recursive directory loop")
+
+// job - файл для поиска или ошибка, возникшая при обходе каталогов
+type job struct {
+	path string
+	err  error
+}
+
+// Функция joinPath добавляет к каталогу dir имя name. Пустой dir означает
+// текущий каталог, заданный по умолчанию, и в выводе не указывается.
+func joinPath(dir, name string) string {
+	if dir == "" {
+		return name
+	}
+	if os.IsPathSeparator(dir[len(dir)-1]) {
+		return dir + name
+	}
+	return dir + string(os.PathSeparator) + name
+}
+
+// Функция included сообщает, что файл с именем name проходит фильтры --include и --exclude
+func included(name string) bool {
+	for _, p := range excludes {
+		if matchSegment(p, name) {
+			return false
+		}
+	}
+	if len(includes) == 0 {
+		return true
+	}
+	for _, p := range includes {
+		if matchSegment(p, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Функция skipDir сообщает, что каталог с именем name исключен --exclude-dir
+// или является каталогом git
+func skipDir(name string) bool {
+	if name == ".git" && !noIgnore {
+		return true
+	}
+	for _, p := range excludeDirs {
+		if matchSegment(p, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Функция walkArgs передает в emit файлы для поиска в порядке аргументов,
+// с флагами -r и -R обходя каталоги. Без аргументов с -r обходится текущий каталог,
+// а без -r читается Stdin.
+func walkArgs(args []string, emit func(job)) {
+	if len(args) == 0 {
+		if recursive || dereference {
+			walkDir("", nil, nil, emit)
+		} else {
+			emit(job{path: "-"})
+		}
+		return
+	}
+	for _, arg := range args {
+		if arg == "-" {
+			emit(job{path: arg})
+			continue
+		}
+		if !recursive && !dereference {
+			if included(filepath.Base(arg)) {
+				emit(job{path: arg})
+			}
+			continue
+		}
+		// символические ссылки в аргументах открываются и с -r
+		info, err := os.Stat(arg)
+		switch {
+		case err != nil:
+			emit(job{path: arg, err: err})
+		case info.IsDir():
+			walkDir(arg, nil, []os.FileInfo{info}, emit)
+		case included(filepath.Base(arg)):
+			emit(job{path: arg})
+		}
+	}
+}
+
+// Функция walkDir рекурсивно обходит каталог dir в лексикографическом порядке.
+// ign - правила .gitignore родительских каталогов, ancestors - сами каталоги
+// для обнаружения циклов по символическим ссылкам с флагом -R.
+func walkDir(dir string, ign *ignoreList, ancestors []os.FileInfo, emit func(job)) {
+	path := dir
+	if path == "" {
+		path = "."
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		emit(job{path: path, err: err})
+		return
+	}
+	if !noIgnore {
+		ign = loadIgnore(path, ign)
+	}
+
+	for _, e := range entries {
+		p := joinPath(dir, e.Name())
+		t := e.Type()
+		if t&os.ModeSymlink != 0 {
+			// с -r символические ссылки внутри каталогов пропускаются
+			if !dereference {
+				continue
+			}
+			info, err := os.Stat(p)
+			if err != nil {
+				emit(job{path: p, err: err})
+				continue
+			}
+			t = info.Mode().Type()
+		}
+
+		switch {
+		case t.IsDir():
+			if skipDir(e.Name()) || ign.ignored(p, true) {
+				continue
+			}
+			var info os.FileInfo
+			if dereference {
+				if info, err = os.Stat(p); err != nil {
+					emit(job{path: p, err: err})
+					continue
+				}
+				if inLoop(info, ancestors) {
+					emit(job{path: p, err: fmt.Errorf("%s: %w", p, errLoop)})
+					continue
+				}
+			}
+			walkDir(p, ign, append(ancestors, info), emit)
+		case t.IsRegular():
+			if included(e.Name()) && !ign.ignored(p, false) {
+				emit(job{path: p})
+			}
+		}
+	}
+}
+
+// Функция inLoop сообщает, что каталог info уже есть среди обходимых каталогов
+func inLoop(info os.FileInfo, ancestors []os.FileInfo) bool {
+	for _, a := range ancestors {
+		if a != nil && os.SameFile(a, info) {
+			return true
+		}
+	}
+	return false
+}
+
+// result - вывод поиска в одном файле
+type result struct {
+	out []byte
+	n   int
+	err error
+}
+
+// task - файл, передаваемый в пул, и канал для его результата
+type task struct {
+	job
+	done chan result
+}
+
+// Функция grepFiles ищет строки в файлах пулом из workers горутин и выводит
+// результаты в w в порядке файлов. Вывод каждого файла накапливается в памяти,
+// а число одновременно обрабатываемых файлов ограничено. Возвращает итоги поиска.
+func grepFiles(w *bufio.Writer, args []string, m grep.Matcher, prefix bool, workers int) stats {
+	queue := make(chan task, 2*workers)
+	work := make(chan task)
+
+	go func() {
+		walkArgs(args, func(j job) {
+			t := task{j, make(chan result, 1)}
+			queue <- t
+			work <- t
+		})
+		close(queue)
+		close(work)
+	}()
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			for t := range work {
+				if t.err != nil {
+					t.done <- result{err: t.err}
+					continue
+				}
+				var buf bytes.Buffer
+				n, err := grepFile(bufio.NewWriter(&buf), t.path, prefix, m)
+				t.done <- result{buf.Bytes(), n, err}
+			}
+		}()
+	}
+
+	// группы строк разных файлов тоже разделяются
+	_, _, grouped := contextLines()
+	var st stats
+	printed := false
+	for t := range queue {
+		r := <-t.done
+		if len(r.out) > 0 {
+			if grouped && printed {
+				w.WriteString(palette.wrap(palette.se, groupSeparator))
+				w.WriteByte('\n')
+			}
+			printed = true
+		}
+		w.Write(r.out)
+		w.Flush()
+		if r.err != nil {
+			fmt.Fprintln(os.Stderr, r.err)
+		} else {
+			st.add(r.n)
+		}
+	}
+	return st
+}
+
+// Функция openFile открывает файл для поиска и возвращает его имя для вывода.
+// Имя "-" означает Stdin.
+func openFile(file string) (io.ReadCloser, string, error) {
+	if file == "-" {
+		return io.NopCloser(os.Stdin), stdinName, nil
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, file, err
+	}
+	return f, file, nil
+}
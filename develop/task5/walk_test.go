@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeTree создает в каталоге dir файлы с заданным содержимым
+func writeTree(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, data := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// walkPaths возвращает пути, которые walkArgs передает для поиска
+func walkPaths(args []string) []string {
+	var paths []string
+	walkArgs(args, func(j job) { paths = append(paths, filepath.ToSlash(j.path)) })
+	return paths
+}
+
+func TestWalkArgs(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"a.go":           "",
+		"b.txt":          "",
+		"sub/c.go":       "",
+		"sub/d.log":      "",
+		"vendor/e.go":    "",
+		".git/config":    "",
+		".gitignore":     "*.log\n",
+		"sub/.gitignore": "!d.log\n",
+	})
+	if err := os.Symlink(filepath.Join(dir, "sub"), filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	recursive = true
+	defer func() {
+		recursive, dereference, noIgnore = false, false, false
+		includes, excludes, excludeDirs = nil, nil, nil
+	}()
+
+	base := filepath.ToSlash(dir)
+	expected := []string{base + "/.gitignore", base + "/a.go", base + "/b.txt",
+		base + "/sub/.gitignore", base + "/sub/c.go", base + "/sub/d.log", base + "/vendor/e.go"}
+	if res := walkPaths([]string{dir}); !reflect.DeepEqual(res, expected) {
+		t.Errorf("walkArgs = %q, expected %q", res, expected)
+	}
+
+	includes, excludeDirs = stringList{"*.go"}, stringList{"vendor"}
+	expected = []string{base + "/a.go", base + "/sub/c.go"}
+	if res := walkPaths([]string{dir}); !reflect.DeepEqual(res, expected) {
+		t.Errorf("walkArgs with --include and --exclude-dir = %q, expected %q", res, expected)
+	}
+
+	// -R обходит символические ссылки внутри каталогов
+	dereference = true
+	expected = []string{base + "/a.go", base + "/link/c.go", base + "/sub/c.go"}
+	if res := walkPaths([]string{dir}); !reflect.DeepEqual(res, expected) {
+		t.Errorf("walkArgs with -R = %q, expected %q", res, expected)
+	}
+	dereference = false
+
+	noIgnore, includes, excludeDirs, excludes = true, nil, nil, stringList{".git*", "*.go", "*.txt"}
+	expected = []string{base + "/.git/config", base + "/sub/d.log"}
+	if res := walkPaths([]string{dir}); !reflect.DeepEqual(res, expected) {
+		t.Errorf("walkArgs with --no-ignore = %q, expected %q", res, expected)
+	}
+}
+
+func TestWalkLoop(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"sub/a": ""})
+	if err := os.Symlink(dir, filepath.Join(dir, "sub", "up")); err != nil {
+		t.Fatal(err)
+	}
+	dereference = true
+	defer func() { dereference = false }()
+
+	var errs int
+	walkArgs([]string{dir}, func(j job) {
+		if j.err != nil {
+			errs++
+		}
+	})
+	if errs != 1 {
+		t.Errorf("walkArgs with a symlink loop: %d errors, expected 1", errs)
+	}
+}
+
+func TestGrepFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{"bin": "a\x00\na\n", "empty": "b\n"}
+	for i := 0; i < 20; i++ {
+		files[string(rune('c'+i))] = "a\nb\n"
+	}
+	writeTree(t, dir, files)
+	base := dir + string(os.PathSeparator)
+
+	recursive = true
+	defer func() { recursive, listNonMatching, noBinary = false, false, false }()
+
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	m := mustCompile(t, "a")
+	if st := grepFiles(w, []string{dir}, m, true, 4); st != (stats{22, 21, 21}) {
+		t.Errorf("grepFiles = %+v, expected {files:22 matched:21 lines:21}", st)
+	}
+	// сообщение о совпадении в двоичном файле выводится в stderr
+	expected := ""
+	for i := 0; i < 20; i++ {
+		expected += base + string(rune('c'+i)) + ":a\n"
+	}
+	if buf.String() != expected {
+		t.Errorf("grepFiles output:\n%s\nexpected:\n%s", buf.String(), expected)
+	}
+
+	buf.Reset()
+	listNonMatching, noBinary = true, true
+	grepFiles(w, []string{dir}, m, true, 4)
+	if expected := base + "bin\n" + base + "empty\n"; buf.String() != expected {
+		t.Errorf("grepFiles with -L -I = %q, expected %q", buf.String(), expected)
+	}
+}
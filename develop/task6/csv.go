@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// csvCutter выбирает поля записей CSV (--csv). Записи читаются по RFC 4180:
+// поля в кавычках могут содержать разделители, кавычки и переводы строк,
+// а выводимые поля заключаются в кавычки, если это необходимо.
+type csvCutter struct {
+	// comma - разделитель полей на входе, out - на выходе
+	comma rune
+	out   rune
+	// rs - отрезки полей, выводимых в порядке записи,
+	// fs - отрезки полей, выводимых в порядке списка (--reorder, -F)
+	rs [][]int
+	fs [][]int
+	// names - имена полей -F, номера которых определяются по первой записи
+	names []string
+}
+
+// Функция csvRune возвращает символ-разделитель CSV, заданный строкой s
+func csvRune(s string) (rune, error) {
+	r, size := utf8.DecodeRuneInString(s)
+	if size != len(s) || r == utf8.RuneError || r == '"' || r == '\r' || r == '\n' {
+		return 0, fmt.Errorf("invalid CSV delimiter '%s'", s)
+	}
+	return r, nil
+}
+
+// Функция newCSVCutter проверяет флаги и возвращает csvCutter для полей -f или -F
+func newCSVCutter() (*csvCutter, error) {
+	if bytesList != "" || charsList != "" {
+		return nil, errors.New("--csv may be specified only when operating on fields")
+	}
+	if whitespace || regexDelim {
+		return nil, errors.New("--csv is incompatible with -w and --regex-delimiter")
+	}
+	if fields != "" && names != "" {
+		return nil, errors.New("only one list may be specified")
+	}
+	if fields == "" && names == "" {
+		return nil, errors.New("you must specify a list of fields or field names")
+	}
+	if reorder && complement {
+		return nil, errReorderComplement
+	}
+
+	c := &csvCutter{comma: ','}
+	var err error
+	if isFlagSet("d") {
+		if c.comma, err = csvRune(delim); err != nil {
+			return nil, err
+		}
+	}
+	c.out = c.comma
+	if isFlagSet("output-delimiter") {
+		if c.out, err = csvRune(outDelim); err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case names != "":
+		c.names = strings.Split(names, ",")
+	case reorder:
+		c.fs, err = parseOrder(fields)
+	default:
+		var fs [][]int
+		fs, err = parseFields(fields)
+		c.rs = normalize(fs, complement)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// resolveNames заменяет имена полей -F их номерами в заголовке header.
+// Из одинаковых имен выбирается первое.
+func (c *csvCutter) resolveNames(header []string) error {
+	index := make(map[string]int, len(header))
+	for i := len(header) - 1; i >= 0; i-- {
+		index[header[i]] = i + 1
+	}
+	fs := make([][]int, len(c.names))
+	for i, name := range c.names {
+		n, ok := index[name]
+		if !ok {
+			return fmt.Errorf("unknown field name '%s'", name)
+		}
+		fs[i] = []int{n, n}
+	}
+	if complement {
+		c.rs = normalize(fs, true)
+	} else {
+		c.fs = fs
+	}
+	c.names = nil
+	return nil
+}
+
+// cut выводит в w выбранные поля каждой записи CSV из r. Запись из одного поля,
+// как строка без разделителя, выводится целиком или, с флагом -s, не выводится.
+// Выведенное до ошибки сбрасывается в w и при ее возникновении.
+func (c *csvCutter) cut(r io.Reader, w *bufio.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = c.out
+	err := c.cutRecords(r, cw)
+	cw.Flush()
+	if err == nil {
+		err = cw.Error()
+	}
+	if ferr := w.Flush(); err == nil {
+		err = ferr
+	}
+	return err
+}
+
+// cutRecords записывает в cw выбранные поля каждой записи CSV из r
+func (c *csvCutter) cutRecords(r io.Reader, cw *csv.Writer) error {
+	cr := csv.NewReader(r)
+	cr.Comma = c.comma
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if c.names != nil {
+			if err := c.resolveNames(rec); err != nil {
+				return err
+			}
+		}
+
+		out := rec
+		switch {
+		case len(rec) == 1 && separated:
+			continue
+		case len(rec) == 1:
+		case c.fs != nil:
+			out = selectOrdered(rec, c.fs)
+		default:
+			out = selectFields(rec, c.rs)
+		}
+		if err := cw.Write(out); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const csvInput = "id,name,email\n" +
+	"1,\"Doe, John\",j@x.org\n" +
+	"2,\"Say \"\"hi\"\"\",\"multi\nline\"\n" +
+	"3\n"
+
+// testCSV выводит поля csvInput с флагом --csv и сравнивает вывод с expected
+func testCSV(t *testing.T, expected string) {
+	t.Helper()
+	csvMode = true
+	defer func() { csvMode = false }()
+
+	var buf bytes.Buffer
+	if err := execute(strings.NewReader(csvInput), bufio.NewWriter(&buf)); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if buf.String() != expected {
+		t.Errorf("output:\n%s\nexpected:\n%s", buf.String(), expected)
+	}
+}
+
+func TestCSVFields(t *testing.T) {
+	defer func() { fields, separated, complement, reorder = "", false, false, false }()
+
+	fields = "2"
+	testCSV(t, "name\n\"Doe, John\"\n\"Say \"\"hi\"\"\"\n3\n")
+
+	separated, complement = true, true
+	testCSV(t, "id,email\n1,j@x.org\n2,\"multi\nline\"\n")
+
+	fields, separated, complement, reorder = "-1,1", false, false, true
+	testCSV(t, "email,id\nj@x.org,1\n\"multi\nline\",2\n3\n")
+}
+
+func TestCSVNames(t *testing.T) {
+	defer func() { names, complement = "", false }()
+
+	names = "email,name"
+	testCSV(t, "email,name\nj@x.org,\"Doe, John\"\n\"multi\nline\",\"Say \"\"hi\"\"\"\n3\n")
+
+	names, complement = "name", true
+	testCSV(t, "id,email\n1,j@x.org\n2,\"multi\nline\"\n3\n")
+}
+
+func TestCSVErrors(t *testing.T) {
+	defer func() { csvMode, fields, names, bytesList = false, "", "", "" }()
+
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	names = "id"
+	if err := execute(strings.NewReader(csvInput), w); err == nil {
+		t.Error("execute with -F without --csv: expected error")
+	}
+
+	csvMode, names = true, "phone"
+	if err := execute(strings.NewReader(csvInput), w); err == nil || !strings.Contains(err.Error(), "phone") {
+		t.Errorf("execute with an unknown field name: %v, expected error", err)
+	}
+
+	// записи до ошибки чтения выводятся
+	names, fields = "", "1"
+	buf.Reset()
+	if err := execute(strings.NewReader("x,y\na,\"b\n"), w); err == nil || buf.String() != "x\n" {
+		t.Errorf("execute with an unterminated quote = %q, %v, expected \"x\\n\" and error", buf.String(), err)
+	}
+
+	fw := bufio.NewWriterSize(failWriter{}, 16)
+	if err := execute(strings.NewReader(csvInput), fw); !errors.Is(err, errWrite) {
+		t.Errorf("execute with a failing writer: %v, expected %v", err, errWrite)
+	}
+
+	bytesList = "1"
+	if err := execute(strings.NewReader(csvInput), w); err == nil {
+		t.Error("execute with --csv and -b: expected error")
+	}
+}
+
+var errWrite = errors.New("write failed")
+
+// failWriter - io.Writer, всегда возвращающий ошибку errWrite
+type failWriter struct{}
+
+func (failWriter) Write([]byte) (int, error) { return 0, errWrite }
+
+func TestCSVRune(t *testing.T) {
+	if r, err := csvRune(";"); r != ';' || err != nil {
+		t.Errorf("csvRune(\";\") = %q, %v, expected ';', nil", r, err)
+	}
+	for _, s := range []string{"", "::", "\"", "\n"} {
+		if _, err := csvRune(s); err == nil {
+			t.Errorf("csvRune(%q): expected error", s)
+		}
+	}
+}
@@ -5,9 +5,11 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"math"
 	"os"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"testing"
@@ -28,14 +30,36 @@ import (
 */
 
 var fields string
+var bytesList string
+var charsList string
 var delim string
 var separated bool
+var noSplit bool
+var complement bool
+var outDelim string
+var reorder bool
+var regexDelim bool
+var whitespace bool
+var csvMode bool
+var names string
 
 func init() {
 	testing.Init()
 	flag.StringVar(&fields, "f", "", "")
+	flag.StringVar(&bytesList, "b", "", "select only these bytes")
+	flag.StringVar(&charsList, "c", "", "select only these characters")
 	flag.StringVar(&delim, "d", "\t", "")
 	flag.BoolVar(&separated, "s", false, "")
+	flag.BoolVar(&noSplit, "n", false, "with -b: don't split multibyte characters")
+	flag.BoolVar(&complement, "complement", false, "complement the set of selected bytes, characters or fields")
+	flag.StringVar(&outDelim, "output-delimiter", "", "use STRING as the output delimiter, "+
+		"the default is to use the input delimiter for fields and nothing for bytes and characters")
+	flag.BoolVar(&reorder, "reorder", false, "output fields in the order given in -f, allowing repeats "+
+		"and negative indexes counting from the last field")
+	flag.BoolVar(&regexDelim, "regex-delimiter", false, "treat -d as a regular expression matching field delimiters")
+	flag.BoolVar(&whitespace, "w", false, "split fields on runs of spaces and tabs, ignoring leading and trailing ones, like awk")
+	flag.BoolVar(&csvMode, "csv", false, "parse input as CSV (RFC 4180) with -d as the comma and quote output fields as needed")
+	flag.StringVar(&names, "F", "", "with --csv: select fields by names from the header record, in the order given")
 	flag.Parse()
 }
 
@@ -47,6 +71,7 @@ var re3 = regexp.MustCompile(`^\d+\-$`)
 
 var errFieldsOne = errors.New("fields are numbered from 1")
 var errFieldsDecr = errors.New("invalid decreasing range")
+var errReorderComplement = errors.New("--reorder is incompatible with --complement")
 
 // Функция parseFields парсит строку, переданную через флаг -f
 // и возвращает слайс интервалов, отображаемых полей
@@ -106,50 +131,195 @@ func parseFields(s string) (fs [][]int, err error) {
 	return fs, nil
 }
 
-// Функция cutAndPrint печатает поля из диапазонов fs строки s.
-func cutAndPrint(s string, fs [][]int, dr rune) {
-
-	// поиск подстрок, разделенных символом dr
-	var sf []string
-	rs := []rune(s)
-	st := 0
-	match := false
-	for i, r := range rs {
-		if r == dr {
-			match = true
-			if i == st {
-				sf = append(sf, "")
-			} else {
-				sf = append(sf, string(rs[st:i]))
-			}
-			st = i + 1
-		} else if i == len(rs)-1 {
-			sf = append(sf, string(rs[st:]))
+// reOrderItem - элемент списка полей --reorder: номер поля, возможно отрицательный,
+// или диапазон номеров с необязательным концом
+var reOrderItem = regexp.MustCompile(`^(-?\d+)(-(-?\d+)?)?$`)
+
+// Функция parseOrder парсит список полей -f для --reorder и возвращает отрезки
+// в порядке списка. Отрицательные номера отсчитываются от последнего поля: -1 - последнее.
+// Конец math.MaxInt означает последнее поле, а отрезок с началом после конца
+// задает поля в обратном порядке.
+func parseOrder(s string) ([][]int, error) {
+	var fs [][]int
+	for _, item := range strings.Split(s, ",") {
+		m := reOrderItem.FindStringSubmatch(item)
+		if m == nil {
+			return nil, errors.New("invalid fields value")
 		}
+		a, _ := strconv.Atoi(m[1])
+		b := a
+		switch {
+		case m[3] != "":
+			b, _ = strconv.Atoi(m[3])
+		case m[2] != "":
+			b = math.MaxInt
+		}
+		if a == 0 || b == 0 {
+			return nil, errFieldsOne
+		}
+		fs = append(fs, []int{a, b})
 	}
+	return fs, nil
+}
 
-	// строка не содержит разделителя
-	if !match {
-		if separated {
-			return
+// Функция normalize упорядочивает отрезки fs и объединяет пересекающиеся.
+// С complement возвращаются отрезки, не входящие в fs. Как в GNU cut,
+// соседние отрезки не объединяются: при выводе байтов и символов между ними
+// выводится разделитель --output-delimiter.
+func normalize(fs [][]int, complement bool) [][]int {
+	sorted := make([][]int, len(fs))
+	copy(sorted, fs)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i][0] < sorted[j][0] })
+
+	var rs [][]int
+	for _, f := range sorted {
+		if n := len(rs); n > 0 && f[0] <= rs[n-1][1] {
+			rs[n-1][1] = max(rs[n-1][1], f[1])
+			continue
 		}
-		fmt.Println(s)
-		return
+		rs = append(rs, []int{f[0], f[1]})
+	}
+	if !complement {
+		return rs
 	}
 
-	// случай, когда строка оканчивается символом-разделителем
-	if lr, _ := utf8.DecodeLastRuneInString(s); lr == dr {
-		sf = append(sf, "")
+	var cs [][]int
+	next := 1
+	for _, r := range rs {
+		if r[0] > next {
+			cs = append(cs, []int{next, r[0] - 1})
+		}
+		if r[1] == math.MaxInt {
+			return cs
+		}
+		next = r[1] + 1
 	}
+	return append(cs, []int{next, math.MaxInt})
+}
+
+// cutFunc возвращает выбранные части строки и false, если строку не нужно выводить
+type cutFunc func(s string) (string, bool)
+
+// splitFunc делит строку на поля и возвращает false, если в строке нет разделителя
+type splitFunc func(s string) ([]string, bool)
+
+// Функция cutFields возвращает поля из отрезков rs строки s, разделенные sep.
+// Строка без разделителя возвращается целиком или, с флагом -s, не выводится.
+func cutFields(s string, rs [][]int, split splitFunc, sep string) (string, bool) {
+	sf, ok := split(s)
+
+	// строка не содержит разделителя
+	if !ok {
+		return s, !separated
+	}
+
+	return strings.Join(selectFields(sf, rs), sep), true
+}
 
-	// выбор подстрок (полей), входящих в диапазоны fs
+// Функция selectFields возвращает поля из sf, входящие в отрезки rs, в порядке sf
+func selectFields(sf []string, rs [][]int) []string {
 	var outSlice []string
 	for i, f := range sf {
-		if indexInSegments(i+1, fs) {
+		if len(rs) > 0 && indexInSegments(i+1, rs) {
 			outSlice = append(outSlice, f)
 		}
 	}
-	fmt.Println(strings.Join(outSlice, string(dr)))
+	return outSlice
+}
+
+// Функция cutFieldsOrdered возвращает поля строки s из отрезков fs в порядке отрезков,
+// разделенные sep (--reorder). Номера за пределами строки пропускаются.
+func cutFieldsOrdered(s string, fs [][]int, split splitFunc, sep string) (string, bool) {
+	sf, ok := split(s)
+	if !ok {
+		return s, !separated
+	}
+	return strings.Join(selectOrdered(sf, fs), sep), true
+}
+
+// Функция selectOrdered возвращает поля из sf, входящие в отрезки fs, в порядке отрезков.
+// Номера за пределами sf пропускаются.
+func selectOrdered(sf []string, fs [][]int) []string {
+	// resolve переводит номер поля в индекс в sf, отрицательный - от конца строки
+	n := len(sf)
+	resolve := func(i int) int {
+		switch {
+		case i == math.MaxInt:
+			return n - 1
+		case i < 0:
+			return n + i
+		}
+		return i - 1
+	}
+	var outSlice []string
+	for _, f := range fs {
+		a, b := resolve(f[0]), resolve(f[1])
+		// отрезок без конца, начинающийся после последнего поля, пуст
+		if f[1] == math.MaxInt && a > b {
+			continue
+		}
+		// отрезок ограничивается полями строки с сохранением направления
+		lo, hi := max(min(a, b), 0), min(max(a, b), n-1)
+		if lo > hi {
+			continue
+		}
+		if a <= b {
+			outSlice = append(outSlice, sf[lo:hi+1]...)
+			continue
+		}
+		for i := hi; i >= lo; i-- {
+			outSlice = append(outSlice, sf[i])
+		}
+	}
+	return outSlice
+}
+
+// Функция cutBytes возвращает байты из отрезков rs строки s, отделяя части
+// из разных отрезков строкой sep
+func cutBytes(s string, rs [][]int, sep string) string {
+	var b strings.Builder
+	for k, r := range rs {
+		if r[0] > len(s) {
+			break
+		}
+		if k > 0 {
+			b.WriteString(sep)
+		}
+		b.WriteString(s[r[0]-1 : min(r[1], len(s))])
+	}
+	return b.String()
+}
+
+// Функция cutChars возвращает символы из отрезков rs строки s, отделяя части
+// из разных отрезков строкой sep. Каждый байт, не являющийся частью
+// символа UTF-8, считается отдельным символом. С byBytes отрезки задают
+// позиции байтов, и, как в POSIX cut -b -n, символ выбирается целиком,
+// если выбран его последний байт.
+func cutChars(s string, rs [][]int, sep string, byBytes bool) string {
+	var b strings.Builder
+	// k - текущий отрезок, last - отрезок последнего выбранного символа
+	k, last := 0, -1
+	pos := 0
+	for i := 0; i < len(s) && k < len(rs); {
+		_, size := utf8.DecodeRuneInString(s[i:])
+		if byBytes {
+			pos += size
+		} else {
+			pos++
+		}
+		for k < len(rs) && rs[k][1] < pos {
+			k++
+		}
+		if k < len(rs) && rs[k][0] <= pos {
+			if last >= 0 && last != k {
+				b.WriteString(sep)
+			}
+			last = k
+			b.WriteString(s[i : i+size])
+		}
+		i += size
+	}
+	return b.String()
 }
 
 // Функция indexInSegments возвращает true, если i принадлежит одному из отрезков в segs
@@ -161,27 +331,173 @@ func indexInSegments(i int, segs [][]int) bool {
 	return res
 }
 
-func main() {
-	if fields == "" {
-		fmt.Fprintln(os.Stderr, "fields are not specified")
-		return
+// Функция isFlagSet сообщает, передан ли флаг name в командной строке
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// Функция newCutter проверяет флаги и возвращает функцию, выбирающую
+// из строки байты (-b), символы (-c) или поля (-f)
+func newCutter() (cutFunc, error) {
+	lists := 0
+	for _, l := range []string{fields, bytesList, charsList} {
+		if l != "" {
+			lists++
+		}
+	}
+	if lists == 0 {
+		return nil, errors.New("you must specify a list of bytes, characters, or fields")
+	}
+	if lists > 1 {
+		return nil, errors.New("only one list may be specified")
+	}
+	if fields == "" && (isFlagSet("d") || regexDelim || whitespace) {
+		return nil, errors.New("an input delimiter may be specified only when operating on fields")
+	}
+	if fields == "" && separated {
+		return nil, errors.New("suppressing non-delimited lines makes sense only when operating on fields")
 	}
 
-	fs, err := parseFields(fields)
+	if reorder {
+		if fields == "" {
+			return nil, errors.New("--reorder may be specified only when operating on fields")
+		}
+		if complement {
+			return nil, errReorderComplement
+		}
+		fs, err := parseOrder(fields)
+		if err != nil {
+			return nil, err
+		}
+		split, sep, err := fieldSplitter()
+		if err != nil {
+			return nil, err
+		}
+		return func(s string) (string, bool) { return cutFieldsOrdered(s, fs, split, sep) }, nil
+	}
+
+	list := fields + bytesList + charsList
+	fs, err := parseFields(list)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		return
+		return nil, err
 	}
+	rs := normalize(fs, complement)
 
-	// проверка того, что в -d передан один символ-руна
-	if dn := utf8.RuneCountInString(delim); dn != 1 {
-		fmt.Fprintln(os.Stderr, "the delimiter must be a single character")
-		return
+	switch {
+	case bytesList != "" && noSplit:
+		return func(s string) (string, bool) { return cutChars(s, rs, outDelim, true), true }, nil
+	case bytesList != "":
+		return func(s string) (string, bool) { return cutBytes(s, rs, outDelim), true }, nil
+	case charsList != "":
+		return func(s string) (string, bool) { return cutChars(s, rs, outDelim, false), true }, nil
+	}
+
+	split, sep, err := fieldSplitter()
+	if err != nil {
+		return nil, err
 	}
-	dr, _ := utf8.DecodeRuneInString(delim)
+	return func(s string) (string, bool) { return cutFields(s, rs, split, sep) }, nil
+}
+
+// Функция isBlank сообщает, является ли r пробелом или табуляцией
+func isBlank(r rune) bool {
+	return r == ' ' || r == '\t'
+}
 
-	sc := bufio.NewScanner(os.Stdin)
-	for sc.Scan() {
-		cutAndPrint(sc.Text(), fs, dr)
+// Функция fieldSplitter возвращает функцию, делящую строку на поля по разделителю -d,
+// регулярному выражению -d (--regex-delimiter) или пробелам (-w), и строку,
+// которой соединяются выводимые поля. Поля, разделенные регулярным выражением
+// или пробелами, по умолчанию соединяются пробелом.
+func fieldSplitter() (splitFunc, string, error) {
+	if whitespace && (isFlagSet("d") || regexDelim) {
+		return nil, "", errors.New("-w is incompatible with -d and --regex-delimiter")
+	}
+	var split splitFunc
+	sep := delim
+	switch {
+	case whitespace:
+		split = func(s string) ([]string, bool) {
+			return strings.FieldsFunc(s, isBlank), strings.ContainsAny(s, " \t")
+		}
+		sep = " "
+	case regexDelim:
+		re, err := regexp.Compile(delim)
+		if err != nil {
+			return nil, "", err
+		}
+		if re.MatchString("") {
+			return nil, "", errors.New("the delimiter regular expression matches the empty string")
+		}
+		split = func(s string) ([]string, bool) {
+			sf := re.Split(s, -1)
+			return sf, len(sf) > 1
+		}
+		sep = " "
+	case delim == "":
+		return nil, "", errors.New("the delimiter must not be empty")
+	default:
+		// строка-разделитель, в том числе из одного символа, ищется без регулярных выражений
+		d := delim
+		split = func(s string) ([]string, bool) {
+			sf := strings.Split(s, d)
+			return sf, len(sf) > 1
+		}
+	}
+	if isFlagSet("output-delimiter") {
+		sep = outDelim
+	}
+	return split, sep, nil
+}
+
+// Функция cutLines выводит в w части каждой строки r, выбранные cut
+func cutLines(r io.Reader, w *bufio.Writer, cut cutFunc) error {
+	br := bufio.NewReader(r)
+	for {
+		s, err := br.ReadString('\n')
+		if len(s) > 0 {
+			if out, ok := cut(strings.TrimSuffix(s, "\n")); ok {
+				w.WriteString(out)
+				w.WriteByte('\n')
+			}
+		}
+		if err == io.EOF {
+			return w.Flush()
+		}
+		if err != nil {
+			w.Flush()
+			return err
+		}
+	}
+}
+
+// Функция execute выводит в w части строк или, с флагом --csv, записей CSV из r,
+// выбранные флагами
+func execute(r io.Reader, w *bufio.Writer) error {
+	if csvMode {
+		c, err := newCSVCutter()
+		if err != nil {
+			return err
+		}
+		return c.cut(r, w)
+	}
+	if names != "" {
+		return errors.New("field names may be specified only with --csv")
+	}
+	cut, err := newCutter()
+	if err != nil {
+		return err
+	}
+	return cutLines(r, w, cut)
+}
+
+func main() {
+	if err := execute(os.Stdin, bufio.NewWriter(os.Stdout)); err != nil {
+		fmt.Fprintln(os.Stderr, err)
 	}
 }
@@ -1,8 +1,11 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"math"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -88,3 +91,199 @@ func TestIndexInSegmentsFalse(t *testing.T) {
 		t.Logf("indexInSegments(%d, %v) = %v, expected: true", i, segs, res)
 	}
 }
+
+func TestNormalize(t *testing.T) {
+	fs := [][]int{{4, 6}, {1, 2}, {5, 8}, {3, 3}, {12, math.MaxInt}}
+
+	expected := [][]int{{1, 2}, {3, 3}, {4, 8}, {12, math.MaxInt}}
+	if res := normalize(fs, false); !reflect.DeepEqual(res, expected) {
+		t.Errorf("normalize(%v, false) = %v, expected %v", fs, res, expected)
+	}
+	expected = [][]int{{9, 11}}
+	if res := normalize(fs, true); !reflect.DeepEqual(res, expected) {
+		t.Errorf("normalize(%v, true) = %v, expected %v", fs, res, expected)
+	}
+	expected = [][]int{{1, 1}, {3, math.MaxInt}}
+	if res := normalize([][]int{{2, 2}}, true); !reflect.DeepEqual(res, expected) {
+		t.Errorf("normalize([[2 2]], true) = %v, expected %v", res, expected)
+	}
+}
+
+func TestCutBytes(t *testing.T) {
+	rs := [][]int{{1, 2}, {4, 5}, {8, math.MaxInt}}
+	cases := map[string]string{"abcdefghij": "ab:de:hij", "abcd": "ab:d", "a": "a", "": ""}
+	for s, expected := range cases {
+		if res := cutBytes(s, rs, ":"); res != expected {
+			t.Errorf("cutBytes(%q) = %q, expected %q", s, res, expected)
+		}
+	}
+}
+
+func TestCutChars(t *testing.T) {
+	s := "привет, мир"
+	if res := cutChars(s, [][]int{{1, 2}, {9, math.MaxInt}}, ":", false); res != "пр:мир" {
+		t.Errorf("cutChars(%q) = %q, expected \"пр:мир\"", s, res)
+	}
+
+	// с -b -n символ выбирается, если выбран его последний байт
+	cases := []struct {
+		rs       [][]int
+		expected string
+	}{
+		{[][]int{{1, 3}}, "п"},
+		{[][]int{{2, 4}}, "пр"},
+		{[][]int{{3, 3}}, ""},
+		{[][]int{{1, 1}, {2, 2}}, "п"},
+		{[][]int{{13, 15}}, ", "},
+	}
+	for _, c := range cases {
+		if res := cutChars(s, c.rs, ":", true); res != c.expected {
+			t.Errorf("cutChars(%q, %v) with -n = %q, expected %q", s, c.rs, res, c.expected)
+		}
+	}
+
+	// байт, не являющийся частью символа UTF-8, - отдельный символ
+	if res := cutChars("a\xffb", [][]int{{2, 3}}, "", false); res != "\xffb" {
+		t.Errorf("cutChars with invalid UTF-8 = %q, expected \"\\xffb\"", res)
+	}
+}
+
+// colonSplit делит строку на поля по ':'
+func colonSplit(s string) ([]string, bool) {
+	sf := strings.Split(s, ":")
+	return sf, len(sf) > 1
+}
+
+func TestCutFields(t *testing.T) {
+	rs := normalize([][]int{{2, 2}}, true)
+	if res, ok := cutFields("a:b:c", rs, colonSplit, "--"); res != "a--c" || !ok {
+		t.Errorf("cutFields with --complement = %q, %v, expected \"a--c\", true", res, ok)
+	}
+	if res, ok := cutFields("a:b:", [][]int{{3, 3}}, colonSplit, ":"); res != "" || !ok {
+		t.Errorf("cutFields(\"a:b:\") = %q, %v, expected \"\", true", res, ok)
+	}
+
+	separated = true
+	defer func() { separated = false }()
+	if _, ok := cutFields("abc", rs, colonSplit, ":"); ok {
+		t.Error("cutFields without a delimiter and -s: expected no output")
+	}
+}
+
+func TestNewCutter(t *testing.T) {
+	defer func() { fields, bytesList, charsList, separated = "", "", "", false }()
+
+	if _, err := newCutter(); err == nil {
+		t.Error("newCutter without a list: expected error")
+	}
+	bytesList, charsList = "1", "2"
+	if _, err := newCutter(); err == nil {
+		t.Error("newCutter with -b and -c: expected error")
+	}
+	bytesList, separated = "", true
+	if _, err := newCutter(); err == nil {
+		t.Error("newCutter with -c and -s: expected error")
+	}
+
+	separated = false
+	cut, err := newCutter()
+	if err != nil {
+		t.Fatalf("newCutter with -c 2: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := cutLines(strings.NewReader("abc\nяю\n\nlast"), bufio.NewWriter(&buf), cut); err != nil {
+		t.Fatalf("cutLines: %v", err)
+	}
+	if expected := "b\nю\n\na\n"; buf.String() != expected {
+		t.Errorf("cutLines output = %q, expected %q", buf.String(), expected)
+	}
+}
+
+func TestParseOrder(t *testing.T) {
+	s := "3,1,-1,2-,-3--1,4-2,1"
+	expected := [][]int{{3, 3}, {1, 1}, {-1, -1}, {2, math.MaxInt}, {-3, -1}, {4, 2}, {1, 1}}
+	if res, err := parseOrder(s); err != nil || !reflect.DeepEqual(res, expected) {
+		t.Errorf("parseOrder(%q) = %v, %v, expected %v, nil", s, res, err, expected)
+	}
+	for _, s := range []string{"", "0", "1,", "a", "1--", "2-0"} {
+		if _, err := parseOrder(s); err == nil {
+			t.Errorf("parseOrder(%q): expected error", s)
+		}
+	}
+}
+
+func TestCutFieldsOrdered(t *testing.T) {
+	cases := []struct {
+		fs       string
+		expected string
+	}{
+		{"3,1", "c:a"},
+		{"1,1,2", "a:a:b"},
+		{"-1", "d"},
+		{"-2-", "c:d"},
+		{"3-1", "c:b:a"},
+		{"-1--3", "d:c:b"},
+		{"5,-9,2", "b"},
+		{"5-", ""},
+		{"-9-", "a:b:c:d"},
+		{"2-2000000000", "b:c:d"},
+		{"2000000000-3", "d:c"},
+		{"-2000000000--2", "a:b:c"},
+	}
+	for _, c := range cases {
+		fs, _ := parseOrder(c.fs)
+		if res, ok := cutFieldsOrdered("a:b:c:d", fs, colonSplit, ":"); res != c.expected || !ok {
+			t.Errorf("cutFieldsOrdered(-f %s) = %q, %v, expected %q, true", c.fs, res, ok, c.expected)
+		}
+	}
+	fs, _ := parseOrder("2,1")
+	if res, ok := cutFieldsOrdered("abc", fs, colonSplit, ":"); res != "abc" || !ok {
+		t.Errorf("cutFieldsOrdered without a delimiter = %q, %v, expected \"abc\", true", res, ok)
+	}
+}
+
+func TestFieldSplitter(t *testing.T) {
+	defer func() { delim, regexDelim, whitespace, outDelim = "\t", false, false, "" }()
+
+	cases := []struct {
+		delim      string
+		regex      bool
+		whitespace bool
+		s          string
+		expected   []string
+		ok         bool
+	}{
+		{"\t", false, false, "a\tb", []string{"a", "b"}, true},
+		{"::", false, false, "a::b:c::", []string{"a", "b:c", ""}, true},
+		{"::", false, false, "a:b", []string{"a:b"}, false},
+		{"", true, false, "a, b,c", nil, false},
+		{", *", true, false, "a, b,  c", []string{"a", "b", "c"}, true},
+		{"\t", false, true, "  a \t b  c ", []string{"a", "b", "c"}, true},
+		{"\t", false, true, "abc", []string{"abc"}, false},
+	}
+	for _, c := range cases {
+		delim, regexDelim, whitespace = c.delim, c.regex, c.whitespace
+		split, sep, err := fieldSplitter()
+		if c.expected == nil {
+			if err == nil {
+				t.Errorf("fieldSplitter(%q): expected error", c.delim)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("fieldSplitter(%q): %v", c.delim, err)
+		}
+		if sf, ok := split(c.s); !reflect.DeepEqual(sf, c.expected) || ok != c.ok {
+			t.Errorf("split(%q) with -d %q = %q, %v, expected %q, %v", c.s, c.delim, sf, ok, c.expected, c.ok)
+		}
+		if expected := map[bool]string{false: c.delim, true: " "}[c.regex || c.whitespace]; sep != expected {
+			t.Errorf("output delimiter with -d %q = %q, expected %q", c.delim, sep, expected)
+		}
+	}
+
+	// регулярное выражение, совпадающее с пустой строкой, не может быть разделителем
+	delim, regexDelim, whitespace = " *", true, false
+	if _, _, err := fieldSplitter(); err == nil {
+		t.Error("fieldSplitter with an empty-matching regexp: expected error")
+	}
+}